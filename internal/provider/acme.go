@@ -0,0 +1,308 @@
+package provider
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme"
+)
+
+const (
+	acmeChallengeHTTP01 = "http-01"
+	acmeChallengeDNS01  = "dns-01"
+
+	// acmeTimeout bounds a whole issuance, including waiting for challenges.
+	acmeTimeout = 10 * time.Minute
+	// acmeCleanupTimeout bounds each DNS-01 cleanup command.
+	acmeCleanupTimeout = time.Minute
+)
+
+// acmeIssuer obtains certificates from an ACME directory.
+type acmeIssuer struct {
+	directoryURL string
+	// directoryCAPEM is trusted in addition to the system roots, for directories
+	// like Pebble which use their own CA.
+	directoryCAPEM string
+	accountKey     crypto.Signer
+	email          string
+	eabKeyID       string
+	// eabHMACKey is the base64url encoded external account binding MAC key.
+	eabHMACKey string
+
+	// httpListenAddress is where HTTP-01 challenges are served. DNS-01 is used
+	// instead when dnsPresentCommand is set.
+	httpListenAddress string
+	dnsPresentCommand string
+	dnsCleanupCommand string
+}
+
+// issue orders a certificate for the domains with a new private key and returns
+// the PEM bundle of the key and certificate chain in leaf-to-root order. The
+// returned warnings are the failures of DNS-01 cleanup commands, which do not
+// fail the issuance.
+func (a *acmeIssuer) issue(ctx context.Context, domains []string, algorithm keyAlgorithm) (string, []string, error) {
+	ctx, cancel := context.WithTimeout(ctx, acmeTimeout)
+	defer cancel()
+
+	client, err := a.client()
+	if err != nil {
+		return "", nil, err
+	}
+	if err := a.register(ctx, client); err != nil {
+		return "", nil, err
+	}
+
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(domains...))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create order: %w", err)
+	}
+	cleanups, err := a.authorize(ctx, client, order.AuthzURLs)
+	warnings := a.cleanup(cleanups)
+	if err != nil {
+		return "", warnings, err
+	}
+	bundle, err := a.finalize(ctx, client, order.URI, domains, algorithm)
+	return bundle, warnings, err
+}
+
+// finalize requests the certificate of an authorized order.
+func (a *acmeIssuer) finalize(ctx context.Context, client *acme.Client, orderURL string, domains []string, algorithm keyAlgorithm) (string, error) {
+	order, err := client.WaitOrder(ctx, orderURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to wait for order: %w", err)
+	}
+	key, err := generatePrivateKey(algorithm)
+	if err != nil {
+		return "", err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: domains[0]},
+		DNSNames: domains,
+	}, key)
+	if err != nil {
+		return "", fmt.Errorf("failed to create certificate request: %w", err)
+	}
+	der, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		// Directories finalizing asynchronously, like Pebble, may not return the
+		// order location, so wait for the order using the known URL instead.
+		order, waitErr := client.WaitOrder(ctx, orderURL)
+		if waitErr != nil || order.Status != acme.StatusValid {
+			return "", fmt.Errorf("failed to finalize order: %w", err)
+		}
+		if der, err = client.FetchCert(ctx, order.CertURL, true); err != nil {
+			return "", fmt.Errorf("failed to fetch certificate: %w", err)
+		}
+	}
+	chain := make([]*x509.Certificate, len(der))
+	for i, certDER := range der {
+		if chain[i], err = x509.ParseCertificate(certDER); err != nil {
+			return "", fmt.Errorf("failed to parse issued certificate: %w", err)
+		}
+	}
+	return encodeBundlePEM(key, chain[0], chain[1:])
+}
+
+func (a *acmeIssuer) client() (*acme.Client, error) {
+	client := &acme.Client{
+		Key:          a.accountKey,
+		DirectoryURL: a.directoryURL,
+		UserAgent:    "terraform-provider-clearblade-google",
+	}
+	if a.directoryCAPEM != "" {
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM([]byte(a.directoryCAPEM)) {
+			return nil, fmt.Errorf("no certificates found in directory CA PEM")
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{RootCAs: roots}
+		client.HTTPClient = &http.Client{Transport: transport}
+	}
+	return client, nil
+}
+
+func (a *acmeIssuer) register(ctx context.Context, client *acme.Client) error {
+	account := &acme.Account{}
+	if a.email != "" {
+		account.Contact = []string{"mailto:" + a.email}
+	}
+	if a.eabKeyID != "" {
+		hmacKey, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(a.eabHMACKey, "="))
+		if err != nil {
+			return fmt.Errorf("failed to decode EAB HMAC key: %w", err)
+		}
+		account.ExternalAccountBinding = &acme.ExternalAccountBinding{KID: a.eabKeyID, Key: hmacKey}
+	}
+	if _, err := client.Register(ctx, account, acme.AcceptTOS); err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return fmt.Errorf("failed to register ACME account: %w", err)
+	}
+	return nil
+}
+
+// dns01Cleanup is a presented DNS-01 record to remove after authorization.
+type dns01Cleanup struct {
+	domain string
+	env    []string
+}
+
+// authorize completes the pending authorizations of an order. It returns the
+// DNS-01 records which were presented, also on failure, for cleanup.
+func (a *acmeIssuer) authorize(ctx context.Context, client *acme.Client, authzURLs []string) ([]dns01Cleanup, error) {
+	var cleanups []dns01Cleanup
+	challengeType := acmeChallengeHTTP01
+	if a.dnsPresentCommand != "" {
+		challengeType = acmeChallengeDNS01
+	}
+
+	var responder *http01Responder
+	if challengeType == acmeChallengeHTTP01 {
+		var err error
+		if responder, err = startHTTP01Responder(a.httpListenAddress); err != nil {
+			return nil, err
+		}
+		defer responder.close()
+	}
+
+	for _, authzURL := range authzURLs {
+		authz, err := client.GetAuthorization(ctx, authzURL)
+		if err != nil {
+			return cleanups, fmt.Errorf("failed to get authorization: %w", err)
+		}
+		if authz.Status == acme.StatusValid {
+			continue
+		}
+		var challenge *acme.Challenge
+		for _, c := range authz.Challenges {
+			if c.Type == challengeType {
+				challenge = c
+				break
+			}
+		}
+		if challenge == nil {
+			return cleanups, fmt.Errorf("%s: no %s challenge offered", authz.Identifier.Value, challengeType)
+		}
+
+		switch challengeType {
+		case acmeChallengeHTTP01:
+			response, err := client.HTTP01ChallengeResponse(challenge.Token)
+			if err != nil {
+				return cleanups, err
+			}
+			responder.set(client.HTTP01ChallengePath(challenge.Token), response)
+		case acmeChallengeDNS01:
+			record, err := client.DNS01ChallengeRecord(challenge.Token)
+			if err != nil {
+				return cleanups, err
+			}
+			env := dns01Env(authz.Identifier.Value, record)
+			// The present command may have created the record before failing
+			cleanups = append(cleanups, dns01Cleanup{domain: authz.Identifier.Value, env: env})
+			if err := runHook(ctx, a.dnsPresentCommand, env); err != nil {
+				return cleanups, fmt.Errorf("%s: DNS-01 present command failed: %w", authz.Identifier.Value, err)
+			}
+		}
+
+		if _, err := client.Accept(ctx, challenge); err != nil {
+			return cleanups, fmt.Errorf("%s: failed to accept challenge: %w", authz.Identifier.Value, err)
+		}
+		if _, err := client.WaitAuthorization(ctx, authz.URI); err != nil {
+			return cleanups, fmt.Errorf("%s: authorization failed: %w", authz.Identifier.Value, err)
+		}
+	}
+	return cleanups, nil
+}
+
+// cleanup runs the DNS-01 cleanup command for each presented record and returns
+// the failures.
+func (a *acmeIssuer) cleanup(cleanups []dns01Cleanup) []string {
+	if a.dnsCleanupCommand == "" {
+		return nil
+	}
+	var warnings []string
+	for _, c := range cleanups {
+		// The issuance context may have expired, cleanup runs regardless.
+		ctx, cancel := context.WithTimeout(context.Background(), acmeCleanupTimeout)
+		if err := runHook(ctx, a.dnsCleanupCommand, c.env); err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: DNS-01 cleanup command failed: %s", c.domain, err))
+		}
+		cancel()
+	}
+	return warnings
+}
+
+// dns01Env returns the environment of the DNS-01 hook commands.
+func dns01Env(domain, record string) []string {
+	return []string{
+		"ACME_DOMAIN=" + domain,
+		"ACME_RECORD_NAME=_acme-challenge." + strings.TrimPrefix(domain, "*.") + ".",
+		"ACME_RECORD_VALUE=" + record,
+	}
+}
+
+// runHook runs a shell command with additional environment variables.
+func runHook(ctx context.Context, command string, env []string) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(), env...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// http01Responder serves HTTP-01 challenge responses while an order is
+// authorized.
+type http01Responder struct {
+	server    *http.Server
+	mu        sync.Mutex
+	responses map[string]string
+}
+
+func startHTTP01Responder(address string) (*http01Responder, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for HTTP-01 challenges: %w", err)
+	}
+	r := &http01Responder{responses: map[string]string{}}
+	r.server = &http.Server{Handler: r, ReadHeaderTimeout: 10 * time.Second}
+	go r.server.Serve(listener)
+	return r, nil
+}
+
+func (r *http01Responder) set(path, response string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.responses[path] = response
+}
+
+func (r *http01Responder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	response, ok := r.responses[req.URL.Path]
+	r.mu.Unlock()
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(response))
+}
+
+func (r *http01Responder) close() {
+	r.server.Close()
+}
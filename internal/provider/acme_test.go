@@ -0,0 +1,297 @@
+package provider
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// The ACME tests run against Pebble, https://github.com/letsencrypt/pebble,
+// with pebble-challtestsrv as its DNS server resolving all names to 127.0.0.1:
+//
+//	pebble-challtestsrv -http01 "" -https01 "" -tlsalpn01 "" -doh "" -defaultIPv6 ""
+//	pebble -config test/config/pebble-config-external-account-bindings.json -dnsserver 127.0.0.1:8053
+//
+//	PEBBLE_DIRECTORY_URL=https://localhost:14000/dir \
+//	PEBBLE_CA_FILE=test/certs/pebble.minica.pem \
+//	PEBBLE_CHALLTESTSRV_URL=http://localhost:8055 \
+//	PEBBLE_EAB_KID=kid-1 PEBBLE_EAB_HMAC_KEY=zWNDZM6eQGHWpSRTPal5eIUYFTu7EajVIoguysqZ9wG44nMEtx3MUAsUDkMTQ12W \
+//	go test ./internal/provider -run ACME
+//
+// HTTP-01 challenges are answered on port 5002, where Pebble validates them.
+
+func testACMEIssuer(t *testing.T) *acmeIssuer {
+	t.Helper()
+	directoryURL := os.Getenv("PEBBLE_DIRECTORY_URL")
+	if directoryURL == "" {
+		t.Skip("PEBBLE_DIRECTORY_URL not set")
+	}
+	var caPEM []byte
+	if caFile := os.Getenv("PEBBLE_CA_FILE"); caFile != "" {
+		var err error
+		if caPEM, err = os.ReadFile(caFile); err != nil {
+			t.Fatal(err)
+		}
+	}
+	accountKey, err := generatePrivateKey(keyAlgorithmECDSAP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &acmeIssuer{
+		directoryURL:      directoryURL,
+		directoryCAPEM:    string(caPEM),
+		accountKey:        accountKey,
+		email:             "test@example.com",
+		eabKeyID:          os.Getenv("PEBBLE_EAB_KID"),
+		eabHMACKey:        os.Getenv("PEBBLE_EAB_HMAC_KEY"),
+		httpListenAddress: ":5002",
+	}
+}
+
+// testACMEIssue issues a certificate and returns the parsed leaf.
+func testACMEIssue(t *testing.T, issuer *acmeIssuer, domains []string) *x509.Certificate {
+	t.Helper()
+	bundle, warnings, err := issuer.issue(context.Background(), domains, keyAlgorithmECDSAP256)
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	if len(warnings) > 0 {
+		t.Errorf("issue warnings: %v", warnings)
+	}
+	parsed, err := parseCertificateBundle([]byte(bundle))
+	if err != nil {
+		t.Fatalf("failed to parse bundle: %v", err)
+	}
+	leaf := parsed.leaf()
+	if strings.Join(leaf.DNSNames, ",") != strings.Join(domains, ",") {
+		t.Errorf("certificate DNS names = %v, want %v", leaf.DNSNames, domains)
+	}
+	return leaf
+}
+
+func TestACMEHTTP01(t *testing.T) {
+	issuer := testACMEIssuer(t)
+	testACMEIssue(t, issuer, []string{"http01.example.test", "www.http01.example.test"})
+}
+
+func TestACMEDNS01(t *testing.T) {
+	issuer := testACMEIssuer(t)
+	challtestsrv := os.Getenv("PEBBLE_CHALLTESTSRV_URL")
+	if challtestsrv == "" {
+		t.Skip("PEBBLE_CHALLTESTSRV_URL not set")
+	}
+	log := t.TempDir() + "/hooks.log"
+	issuer.dnsPresentCommand = fmt.Sprintf(`curl -sf -d "{\"host\":\"$ACME_RECORD_NAME\",\"value\":\"$ACME_RECORD_VALUE\"}" %s/set-txt && echo "present $ACME_DOMAIN" >> %s`, challtestsrv, log)
+	issuer.dnsCleanupCommand = fmt.Sprintf(`curl -sf -d "{\"host\":\"$ACME_RECORD_NAME\"}" %s/clear-txt && echo "cleanup $ACME_DOMAIN" >> %s`, challtestsrv, log)
+
+	testACMEIssue(t, issuer, []string{"dns01.example.test", "*.dns01.example.test"})
+
+	hooks, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The wildcard is authorized separately for the same domain.
+	for _, want := range []string{"present dns01.example.test", "cleanup dns01.example.test"} {
+		if n := strings.Count(string(hooks), want+"\n"); n != 2 {
+			t.Errorf("hook log %q contains %q %d times, want 2", hooks, want, n)
+		}
+	}
+}
+
+func TestACMEDNS01CleanupFailure(t *testing.T) {
+	issuer := testACMEIssuer(t)
+	challtestsrv := os.Getenv("PEBBLE_CHALLTESTSRV_URL")
+	if challtestsrv == "" {
+		t.Skip("PEBBLE_CHALLTESTSRV_URL not set")
+	}
+	issuer.dnsPresentCommand = fmt.Sprintf(`curl -sf -d "{\"host\":\"$ACME_RECORD_NAME\",\"value\":\"$ACME_RECORD_VALUE\"}" %s/set-txt`, challtestsrv)
+	issuer.dnsCleanupCommand = `echo "cannot remove $ACME_RECORD_NAME" && exit 1`
+
+	_, warnings, err := issuer.issue(context.Background(), []string{"cleanup.example.test"}, keyAlgorithmECDSAP256)
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "cannot remove _acme-challenge.cleanup.example.test.") {
+		t.Errorf("warnings = %v, want the cleanup failure", warnings)
+	}
+}
+
+func TestACMEExternalAccountBinding(t *testing.T) {
+	issuer := testACMEIssuer(t)
+	if issuer.eabKeyID == "" {
+		t.Skip("PEBBLE_EAB_KID not set")
+	}
+	testACMEIssue(t, issuer, []string{"eab.example.test"})
+
+	// Pebble requires the binding when configured with EAB keys.
+	unbound := testACMEIssuer(t)
+	unbound.eabKeyID = ""
+	if _, _, err := unbound.issue(context.Background(), []string{"eab.example.test"}, keyAlgorithmECDSAP256); err == nil {
+		t.Error("issue without an external account binding succeeded")
+	}
+}
+
+func TestACMERenewal(t *testing.T) {
+	issuer := testACMEIssuer(t)
+	domains := []string{"renewal.example.test"}
+	leaf := testACMEIssue(t, issuer, domains)
+
+	// Renewal reuses the registered account.
+	renewed := testACMEIssue(t, issuer, domains)
+	if renewed.SerialNumber.Cmp(leaf.SerialNumber) == 0 {
+		t.Error("renewal returned the same certificate")
+	}
+}
+
+func TestACMEModifyPlan(t *testing.T) {
+	domains := []string{"renewal.example.test"}
+	bundle, err := generateSelfSignedBundle(domains[0], domains, keyAlgorithmECDSAP256, 90*24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := parseCertificateBundle([]byte(bundle))
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf := parsed.leaf()
+	daysLeft := int32(time.Until(leaf.NotAfter).Hours() / 24)
+	otherDomains, diags := types.ListValueFrom(context.Background(), types.StringType, []string{"other.example.test"})
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	r := &ACMECertificateResource{}
+	state := testACMEState(t, domains, leaf)
+	for _, tc := range []struct {
+		name    string
+		state   func(*ACMECertificateResourceModel)
+		plan    func(*ACMECertificateResourceModel)
+		reissue bool
+		warning bool
+	}{
+		{name: "unchanged", reissue: false},
+		{
+			name:    "before renewal window",
+			plan:    func(d *ACMECertificateResourceModel) { d.RenewalDays = types.Int32Value(daysLeft - 1) },
+			reissue: false,
+		},
+		{
+			name:    "in renewal window",
+			plan:    func(d *ACMECertificateResourceModel) { d.RenewalDays = types.Int32Value(daysLeft + 1) },
+			reissue: true,
+			warning: true,
+		},
+		{
+			name:    "domains changed",
+			plan:    func(d *ACMECertificateResourceModel) { d.Domains = otherDomains },
+			reissue: true,
+		},
+		{
+			name:    "key_algorithm changed",
+			plan:    func(d *ACMECertificateResourceModel) { d.KeyAlgorithm = types.StringValue(string(keyAlgorithmRSA2048)) },
+			reissue: true,
+		},
+		{
+			name:    "file_name changed",
+			plan:    func(d *ACMECertificateResourceModel) { d.FileName = types.StringValue("other.pem") },
+			reissue: true,
+		},
+		{
+			// The default file name is the first domain
+			name:    "file_name set to the default",
+			plan:    func(d *ACMECertificateResourceModel) { d.FileName = types.StringValue(domains[0] + ".pem") },
+			reissue: false,
+		},
+		{
+			name:    "unparsable not_after",
+			state:   func(d *ACMECertificateResourceModel) { d.NotAfter = types.StringValue("not a time") },
+			reissue: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			state, plan := state, state
+			if tc.state != nil {
+				tc.state(&state)
+				tc.state(&plan)
+			}
+			if tc.plan != nil {
+				tc.plan(&plan)
+			}
+			resp := testACMEModifyPlan(t, r, state, plan)
+			if resp.Diagnostics.HasError() {
+				t.Fatalf("ModifyPlan: %v", resp.Diagnostics)
+			}
+			for _, attribute := range []string{"certificate_pem", "ca_chain_pem", "not_after"} {
+				var value types.String
+				resp.Diagnostics.Append(resp.Plan.GetAttribute(context.Background(), path.Root(attribute), &value)...)
+				if resp.Diagnostics.HasError() {
+					t.Fatalf("GetAttribute(%s): %v", attribute, resp.Diagnostics)
+				}
+				if value.IsUnknown() != tc.reissue {
+					t.Errorf("%s unknown = %t, want %t", attribute, value.IsUnknown(), tc.reissue)
+				}
+			}
+			if (resp.Diagnostics.WarningsCount() > 0) != tc.warning {
+				t.Errorf("warnings = %v, want warning %t", resp.Diagnostics.Warnings(), tc.warning)
+			}
+		})
+	}
+}
+
+func testACMEState(t *testing.T, domains []string, leaf *x509.Certificate) ACMECertificateResourceModel {
+	t.Helper()
+	domainList, diags := types.ListValueFrom(context.Background(), types.StringType, domains)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	return ACMECertificateResourceModel{
+		ProjectId:      types.StringValue("project"),
+		Namespace:      types.StringValue("namespace"),
+		Suffix:         types.StringValue("-acme"),
+		DirectoryURL:   types.StringValue("https://localhost:14000/dir"),
+		DirectoryCAPEM: types.StringNull(),
+		Email:          types.StringNull(),
+		Domains:        domainList,
+		KeyAlgorithm:   types.StringNull(),
+		FileName:       types.StringNull(),
+		RenewalDays:    types.Int32Null(),
+		SecretId:       types.StringValue("namespace-acme"),
+		AccountKeyPEM:  types.StringValue("account key"),
+		CertificatePEM: types.StringValue(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf.Raw}))),
+		CAChainPEM:     types.StringValue(""),
+		NotAfter:       types.StringValue(leaf.NotAfter.UTC().Format(time.RFC3339)),
+	}
+}
+
+func testACMEModifyPlan(t *testing.T, r *ACMECertificateResource, state, plan ACMECertificateResourceModel) *resource.ModifyPlanResponse {
+	t.Helper()
+	ctx := context.Background()
+	schemaResp := &resource.SchemaResponse{}
+	r.Schema(ctx, resource.SchemaRequest{}, schemaResp)
+	s := schemaResp.Schema
+
+	req := resource.ModifyPlanRequest{
+		State: tfsdk.State{Schema: s, Raw: tftypes.NewValue(s.Type().TerraformType(ctx), nil)},
+		Plan:  tfsdk.Plan{Schema: s, Raw: tftypes.NewValue(s.Type().TerraformType(ctx), nil)},
+	}
+	if diags := req.State.Set(ctx, &state); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if diags := req.Plan.Set(ctx, &plan); diags.HasError() {
+		t.Fatal(diags)
+	}
+	resp := &resource.ModifyPlanResponse{Plan: req.Plan}
+	r.ModifyPlan(ctx, req, resp)
+	return resp
+}
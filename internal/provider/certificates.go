@@ -0,0 +1,742 @@
+package provider
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+type keyAlgorithm string
+
+const (
+	keyAlgorithmRSA2048   = keyAlgorithm("RSA2048")
+	keyAlgorithmRSA4096   = keyAlgorithm("RSA4096")
+	keyAlgorithmECDSAP256 = keyAlgorithm("ECDSA_P256")
+	keyAlgorithmECDSAP384 = keyAlgorithm("ECDSA_P384")
+)
+
+var keyAlgorithms = []keyAlgorithm{keyAlgorithmRSA2048, keyAlgorithmRSA4096, keyAlgorithmECDSAP256, keyAlgorithmECDSAP384}
+
+// validateKeyAlgorithm checks the value of a key algorithm attribute.
+func validateKeyAlgorithm(value types.String) error {
+	if value.IsNull() || value.IsUnknown() || slices.Contains(keyAlgorithms, keyAlgorithm(value.ValueString())) {
+		return nil
+	}
+	names := make([]string, len(keyAlgorithms))
+	for i, algorithm := range keyAlgorithms {
+		names[i] = string(algorithm)
+	}
+	return fmt.Errorf("key algorithm must be one of %s, got: %q", strings.Join(names, ", "), value.ValueString())
+}
+
+func generatePrivateKey(algorithm keyAlgorithm) (crypto.Signer, error) {
+	switch algorithm {
+	case keyAlgorithmRSA2048:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case keyAlgorithmRSA4096:
+		return rsa.GenerateKey(rand.Reader, 4096)
+	case keyAlgorithmECDSAP256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case keyAlgorithmECDSAP384:
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported key algorithm %q", algorithm)
+	}
+}
+
+func encodePrivateKeyPEM(key crypto.Signer) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+func encodeCertificatePEM(der []byte) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func randomSerialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+// splitHosts separates IP addresses from DNS names in a list of subject
+// alternative names.
+func splitHosts(hosts []string) ([]string, []net.IP) {
+	var dnsNames []string
+	var ips []net.IP
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			ips = append(ips, ip)
+		} else {
+			dnsNames = append(dnsNames, host)
+		}
+	}
+	return dnsNames, ips
+}
+
+// generateSelfSignedBundle creates a self-signed server certificate and returns it
+// in the combined private key and certificate PEM layout HAProxy expects.
+func generateSelfSignedBundle(commonName string, hosts []string, algorithm keyAlgorithm, validity time.Duration) (string, error) {
+	key, err := generatePrivateKey(algorithm)
+	if err != nil {
+		return "", err
+	}
+	template, err := leafTemplate(commonName, hosts, certificateUsageServer, key.Public(), validity)
+	if err != nil {
+		return "", err
+	}
+	template.Subject.Organization = []string{"ClearBlade"}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return "", fmt.Errorf("failed to create certificate: %w", err)
+	}
+	keyPEM, err := encodePrivateKeyPEM(key)
+	if err != nil {
+		return "", err
+	}
+	return keyPEM + encodeCertificatePEM(der), nil
+}
+
+// selfSignedMatches reports whether cert was created by generateSelfSignedBundle
+// with the given settings.
+func selfSignedMatches(cert *x509.Certificate, commonName string, hosts []string, algorithm keyAlgorithm, validity time.Duration) bool {
+	dnsNames, ips := splitHosts(hosts)
+	if cert.Subject.CommonName != commonName || !slices.Equal(cert.DNSNames, dnsNames) ||
+		!slices.EqualFunc(cert.IPAddresses, ips, net.IP.Equal) || certificateKeyAlgorithm(cert) != algorithm {
+		return false
+	}
+	// Certificate times have a precision of one second and leafTemplate
+	// backdates NotBefore by an hour.
+	return cert.NotAfter.Sub(cert.NotBefore).Round(time.Minute) == validity+time.Hour
+}
+
+// certificateKeyAlgorithm returns the key algorithm of a certificate's public
+// key, or an empty string when it is not one of keyAlgorithms.
+func certificateKeyAlgorithm(cert *x509.Certificate) keyAlgorithm {
+	switch pub := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		switch pub.N.BitLen() {
+		case 2048:
+			return keyAlgorithmRSA2048
+		case 4096:
+			return keyAlgorithmRSA4096
+		}
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P256():
+			return keyAlgorithmECDSAP256
+		case elliptic.P384():
+			return keyAlgorithmECDSAP384
+		}
+	}
+	return ""
+}
+
+type certificateUsage string
+
+const (
+	certificateUsageServer = certificateUsage("server")
+	certificateUsageClient = certificateUsage("client")
+)
+
+// leafTemplate returns the template of a leaf certificate for the public key pub.
+func leafTemplate(commonName string, hosts []string, usage certificateUsage, pub crypto.PublicKey, validity time.Duration) (*x509.Certificate, error) {
+	serial, err := randomSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+	extKeyUsage := x509.ExtKeyUsageServerAuth
+	if usage == certificateUsageClient {
+		extKeyUsage = x509.ExtKeyUsageClientAuth
+	}
+	// Only RSA keys are used for key transport, ECDSA keys just sign.
+	keyUsage := x509.KeyUsageDigitalSignature
+	if _, ok := pub.(*rsa.PublicKey); ok {
+		keyUsage |= x509.KeyUsageKeyEncipherment
+	}
+	dnsNames, ips := splitHosts(hosts)
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		DNSNames:              dnsNames,
+		IPAddresses:           ips,
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              keyUsage,
+		ExtKeyUsage:           []x509.ExtKeyUsage{extKeyUsage},
+		BasicConstraintsValid: true,
+	}, nil
+}
+
+// certificateAuthority is a CA certificate together with its private key.
+type certificateAuthority struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+// generateCA creates a CA certificate. The CA is self-signed when parent is nil,
+// otherwise it is an intermediate CA issued by parent which cannot issue further
+// CAs.
+func generateCA(commonName, organization string, algorithm keyAlgorithm, validity time.Duration, parent *certificateAuthority) (*certificateAuthority, error) {
+	key, err := generatePrivateKey(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := randomSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+	subject := pkix.Name{CommonName: commonName}
+	if organization != "" {
+		subject.Organization = []string{organization}
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               subject,
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	issuerCert, issuerKey := template, key
+	if parent != nil {
+		template.MaxPathLenZero = true
+		issuerCert, issuerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, issuerCert, key.Public(), issuerKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CA certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &certificateAuthority{cert: cert, key: key}, nil
+}
+
+// issue creates a certificate signed by the CA and returns the PEM encoded
+// private key and certificate.
+func (ca *certificateAuthority) issue(commonName string, hosts []string, usage certificateUsage, algorithm keyAlgorithm, validity time.Duration) (string, string, error) {
+	key, err := generatePrivateKey(algorithm)
+	if err != nil {
+		return "", "", err
+	}
+	template, err := leafTemplate(commonName, hosts, usage, key.Public(), validity)
+	if err != nil {
+		return "", "", err
+	}
+	if template.NotAfter.After(ca.cert.NotAfter) {
+		template.NotAfter = ca.cert.NotAfter
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, key.Public(), ca.key)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create certificate: %w", err)
+	}
+	keyPEM, err := encodePrivateKeyPEM(key)
+	if err != nil {
+		return "", "", err
+	}
+	return keyPEM, encodeCertificatePEM(der), nil
+}
+
+// parseCertificateAuthority parses a PEM encoded CA certificate and private key.
+func parseCertificateAuthority(certPEM, keyPEM string) (*certificateAuthority, error) {
+	cert, err := firstCertificate([]byte(certPEM))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CA certificate: %w", err)
+	}
+	block, _ := pem.Decode([]byte(keyPEM))
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode CA private key")
+	}
+	key, err := parsePrivateKey(block)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported CA private key type %T", key)
+	}
+	return &certificateAuthority{cert: cert, key: signer}, nil
+}
+
+// certificateBundle is a parsed HAProxy PEM bundle: a private key and the
+// certificate chain in leaf-to-root order.
+type certificateBundle struct {
+	key   crypto.PrivateKey
+	chain []*x509.Certificate
+}
+
+func (b *certificateBundle) leaf() *x509.Certificate {
+	return b.chain[0]
+}
+
+// parseCertificateBundle parses a combined PEM bundle and checks that it holds
+// exactly one private key matching the leaf certificate, followed by the rest of
+// the chain in leaf-to-root order.
+func parseCertificateBundle(data []byte) (*certificateBundle, error) {
+	bundle := &certificateBundle{}
+	keys := 0
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("certificate %d: %w", len(bundle.chain), err)
+			}
+			bundle.chain = append(bundle.chain, cert)
+		case "PRIVATE KEY", "RSA PRIVATE KEY", "EC PRIVATE KEY":
+			key, err := parsePrivateKey(block)
+			if err != nil {
+				return nil, err
+			}
+			bundle.key = key
+			keys++
+		default:
+			return nil, fmt.Errorf("unexpected PEM block %q", block.Type)
+		}
+	}
+	if len(bytes.TrimSpace(rest)) != 0 {
+		return nil, fmt.Errorf("bundle contains data which is not PEM encoded")
+	}
+	if len(bundle.chain) == 0 {
+		return nil, fmt.Errorf("bundle does not contain a certificate")
+	}
+	if keys != 1 {
+		return nil, fmt.Errorf("bundle must contain exactly one private key, found %d", keys)
+	}
+	signer, ok := bundle.key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", bundle.key)
+	}
+	pub, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !pub.Equal(bundle.leaf().PublicKey) {
+		return nil, fmt.Errorf("private key does not match the leaf certificate %q", bundle.leaf().Subject)
+	}
+	for i := 0; i < len(bundle.chain)-1; i++ {
+		if err := bundle.chain[i].CheckSignatureFrom(bundle.chain[i+1]); err != nil {
+			return nil, fmt.Errorf("certificate %d (%q) is not issued by certificate %d (%q), the chain must be in leaf-to-root order: %w",
+				i, bundle.chain[i].Subject, i+1, bundle.chain[i+1].Subject, err)
+		}
+	}
+	return bundle, nil
+}
+
+func parsePrivateKey(block *pem.Block) (crypto.PrivateKey, error) {
+	var key crypto.PrivateKey
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	return key, nil
+}
+
+// checkCertificateExpiry returns an error naming the first certificate of the
+// chain which is expired at now.
+func checkCertificateExpiry(chain []*x509.Certificate, now time.Time) error {
+	for i, cert := range chain {
+		if now.After(cert.NotAfter) {
+			return fmt.Errorf("certificate %d (%q) expired on %s", i, cert.Subject, cert.NotAfter.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// certificateMetadata describes the leaf certificate of a stored bundle.
+type certificateMetadata struct {
+	Subject           string   `tfsdk:"subject"`
+	Issuer            string   `tfsdk:"issuer"`
+	SANs              []string `tfsdk:"sans"`
+	Serial            string   `tfsdk:"serial"`
+	NotBefore         string   `tfsdk:"not_before"`
+	NotAfter          string   `tfsdk:"not_after"`
+	KeyAlgorithm      string   `tfsdk:"key_algorithm"`
+	SHA256Fingerprint string   `tfsdk:"sha256_fingerprint"`
+}
+
+var certificateMetadataType = types.ObjectType{
+	AttrTypes: map[string]attr.Type{
+		"subject":            types.StringType,
+		"issuer":             types.StringType,
+		"sans":               types.ListType{ElemType: types.StringType},
+		"serial":             types.StringType,
+		"not_before":         types.StringType,
+		"not_after":          types.StringType,
+		"key_algorithm":      types.StringType,
+		"sha256_fingerprint": types.StringType,
+	},
+}
+
+func newCertificateMetadata(cert *x509.Certificate) certificateMetadata {
+	sans := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	fingerprint := sha256.Sum256(cert.Raw)
+	return certificateMetadata{
+		Subject:           cert.Subject.String(),
+		Issuer:            cert.Issuer.String(),
+		SANs:              sans,
+		Serial:            cert.SerialNumber.Text(16),
+		NotBefore:         cert.NotBefore.UTC().Format(time.RFC3339),
+		NotAfter:          cert.NotAfter.UTC().Format(time.RFC3339),
+		KeyAlgorithm:      publicKeyAlgorithm(cert),
+		SHA256Fingerprint: hex.EncodeToString(fingerprint[:]),
+	}
+}
+
+func publicKeyAlgorithm(cert *x509.Certificate) string {
+	switch pub := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA%d", pub.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA_" + pub.Curve.Params().Name
+	default:
+		return cert.PublicKeyAlgorithm.String()
+	}
+}
+
+// firstCertificate returns the first certificate in PEM data, which is the leaf
+// certificate of a bundle.
+func firstCertificate(data []byte) (*x509.Certificate, error) {
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, fmt.Errorf("no certificate found")
+		}
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+}
+
+// encodeCertificateSecret encodes PEM bundles keyed by file name into the JSON
+// payload of a TLS certificate secret.
+func encodeCertificateSecret(certs map[string]string) ([]byte, error) {
+	encoded := make(map[string]string, len(certs))
+	for name, contents := range certs {
+		encoded[name] = base64.StdEncoding.EncodeToString([]byte(contents))
+	}
+	certBytes, err := json.Marshal(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal certificates: %w", err)
+	}
+	return certBytes, nil
+}
+
+// decodeCertificateSecret decodes the JSON payload of a TLS certificate secret,
+// which maps file names to base64 encoded PEM bundles.
+func decodeCertificateSecret(payload []byte) (map[string][]byte, error) {
+	encoded := map[string]string{}
+	if err := json.Unmarshal(payload, &encoded); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal certificates: %w", err)
+	}
+	certs := make(map[string][]byte, len(encoded))
+	for name, value := range encoded {
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to decode certificate: %w", name, err)
+		}
+		certs[name] = decoded
+	}
+	return certs, nil
+}
+
+// pkcs12ToPEM converts a base64 encoded PKCS#12 (PFX) file into the combined
+// private key and certificate chain PEM layout HAProxy expects.
+func pkcs12ToPEM(contentBase64, password string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(contentBase64)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode PKCS#12 content: %w", err)
+	}
+	key, cert, caCerts, err := pkcs12.DecodeChain(data, password)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode PKCS#12 content: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return "", fmt.Errorf("unsupported private key type %T", key)
+	}
+	return encodeBundlePEM(signer, cert, caCerts)
+}
+
+// derToPEM converts a base64 encoded DER certificate, PKCS#8 private key and
+// optional DER chain certificates into the combined PEM layout HAProxy expects.
+func derToPEM(certificateBase64, privateKeyBase64 string, chainBase64 []string) (string, error) {
+	certDER, err := base64.StdEncoding.DecodeString(certificateBase64)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse certificate: %w", err)
+	}
+	keyDER, err := base64.StdEncoding.DecodeString(privateKeyBase64)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode private key: %w", err)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(keyDER)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse PKCS#8 private key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return "", fmt.Errorf("unsupported private key type %T", key)
+	}
+	chain := make([]*x509.Certificate, len(chainBase64))
+	for i, encoded := range chainBase64 {
+		der, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode chain certificate %d: %w", i, err)
+		}
+		if chain[i], err = x509.ParseCertificate(der); err != nil {
+			return "", fmt.Errorf("failed to parse chain certificate %d: %w", i, err)
+		}
+	}
+	return encodeBundlePEM(signer, cert, chain)
+}
+
+// encodeBundlePEM encodes a private key, its certificate and the CA certificates
+// in leaf-to-root order. The CA certificates may be given in any order, as
+// PKCS#12 files do not define one.
+func encodeBundlePEM(key crypto.Signer, cert *x509.Certificate, caCerts []*x509.Certificate) (string, error) {
+	keyPEM, err := encodePrivateKeyPEM(key)
+	if err != nil {
+		return "", err
+	}
+	var buf strings.Builder
+	buf.WriteString(keyPEM)
+	buf.WriteString(encodeCertificatePEM(cert.Raw))
+	remaining := slices.Clone(caCerts)
+	for current := cert; len(remaining) > 0; {
+		i := slices.IndexFunc(remaining, func(ca *x509.Certificate) bool {
+			return current.CheckSignatureFrom(ca) == nil
+		})
+		if i < 0 {
+			return "", fmt.Errorf("certificate %q does not belong to the chain of %q", remaining[0].Subject, cert.Subject)
+		}
+		current = remaining[i]
+		remaining = slices.Delete(remaining, i, i+1)
+		buf.WriteString(encodeCertificatePEM(current.Raw))
+	}
+	return buf.String(), nil
+}
+
+// clientCABundle is a deduplicated set of CA certificates and the CRLs issued by
+// them, in the PEM layout HAProxy expects for ca-file and crl-file.
+type clientCABundle struct {
+	certs []*x509.Certificate
+	crls  []*x509.RevocationList
+}
+
+// fingerprints returns the hex SHA-256 fingerprints of the bundle certificates.
+func (b *clientCABundle) fingerprints() []string {
+	fingerprints := make([]string, len(b.certs))
+	for i, cert := range b.certs {
+		fingerprint := sha256.Sum256(cert.Raw)
+		fingerprints[i] = hex.EncodeToString(fingerprint[:])
+	}
+	return fingerprints
+}
+
+// pem returns the CA certificates, the content of the HAProxy ca-file.
+func (b *clientCABundle) pem() string {
+	var buf strings.Builder
+	for _, cert := range b.certs {
+		buf.WriteString(encodeCertificatePEM(cert.Raw))
+	}
+	return buf.String()
+}
+
+// crlPEM returns the CRLs, the content of the HAProxy crl-file. HAProxy ignores
+// CRLs in the ca-file.
+func (b *clientCABundle) crlPEM() string {
+	var buf strings.Builder
+	for _, crl := range b.crls {
+		buf.Write(pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: crl.Raw}))
+	}
+	return buf.String()
+}
+
+// buildClientCABundle parses PEM encoded CA certificates and CRLs into a bundle,
+// see parseClientCACertificates and parseClientCRLs.
+func buildClientCABundle(caCerts, crls []string, now time.Time) (*clientCABundle, []string, error) {
+	certs, warnings, err := parseClientCACertificates(caCerts, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	parsedCRLs, err := parseClientCRLs(crls, certs, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &clientCABundle{certs: certs, crls: parsedCRLs}, warnings, nil
+}
+
+// parseClientCACertificates parses PEM encoded CA certificates. Every entry of
+// caCerts may hold several certificates. Certificates must be CAs and are
+// deduplicated by fingerprint, keeping the first occurrence. Expired CAs are
+// kept, as removing them may be a separate change, and returned as warnings.
+func parseClientCACertificates(caCerts []string, now time.Time) ([]*x509.Certificate, []string, error) {
+	var warnings []string
+	var certs []*x509.Certificate
+	seen := map[[sha256.Size]byte]bool{}
+	for i, data := range caCerts {
+		rest := []byte(data)
+		found := false
+		for {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+			if block.Type != "CERTIFICATE" {
+				return nil, nil, fmt.Errorf("CA certificate %d: unexpected PEM block %q", i, block.Type)
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("CA certificate %d: %w", i, err)
+			}
+			if !cert.BasicConstraintsValid || !cert.IsCA {
+				return nil, nil, fmt.Errorf("CA certificate %d: %q is not a CA certificate", i, cert.Subject)
+			}
+			if err := checkCertificateExpiry([]*x509.Certificate{cert}, now); err != nil {
+				warnings = append(warnings, fmt.Sprintf("CA certificate %d: %s", i, err))
+			}
+			found = true
+			fingerprint := sha256.Sum256(cert.Raw)
+			if seen[fingerprint] {
+				continue
+			}
+			seen[fingerprint] = true
+			certs = append(certs, cert)
+		}
+		if !found {
+			return nil, nil, fmt.Errorf("CA certificate %d: no certificate found", i)
+		}
+		if len(bytes.TrimSpace(rest)) > 0 {
+			return nil, nil, fmt.Errorf("CA certificate %d: unexpected data after PEM blocks", i)
+		}
+	}
+	return certs, warnings, nil
+}
+
+// parseClientCRLs parses PEM encoded CRLs. Every CRL must be signed by one of
+// certs and not be past its next update.
+func parseClientCRLs(crls []string, certs []*x509.Certificate, now time.Time) ([]*x509.RevocationList, error) {
+	var parsed []*x509.RevocationList
+	for i, data := range crls {
+		block, rest := pem.Decode([]byte(data))
+		if block == nil || block.Type != "X509 CRL" {
+			return nil, fmt.Errorf("CRL %d: no PEM encoded X509 CRL found", i)
+		}
+		if len(bytes.TrimSpace(rest)) > 0 {
+			return nil, fmt.Errorf("CRL %d: unexpected data after PEM block", i)
+		}
+		crl, err := x509.ParseRevocationList(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("CRL %d: %w", i, err)
+		}
+		// HAProxy rejects every client of the CA once its CRL is past NextUpdate
+		if !crl.NextUpdate.IsZero() && now.After(crl.NextUpdate) {
+			return nil, fmt.Errorf("CRL %d: %q expired on %s", i, crl.Issuer, crl.NextUpdate.Format(time.RFC3339))
+		}
+		signed := false
+		for _, cert := range certs {
+			if crl.CheckSignatureFrom(cert) == nil {
+				signed = true
+				break
+			}
+		}
+		if !signed {
+			return nil, fmt.Errorf("CRL %d: %q is not signed by any CA in the bundle", i, crl.Issuer)
+		}
+		parsed = append(parsed, crl)
+	}
+	return parsed, nil
+}
+
+// maxSecretPayloadSize is the Secret Manager limit for a secret version payload.
+const maxSecretPayloadSize = 64 * 1024
+
+type certificateLayout string
+
+const (
+	// certificateLayoutSingle stores all bundles in one secret.
+	certificateLayoutSingle = certificateLayout("single")
+	// certificateLayoutPerCertificate stores every bundle in its own secret and
+	// an index of them in the main secret.
+	certificateLayoutPerCertificate = certificateLayout("per_certificate")
+)
+
+// certificateIndex is the main secret payload of the per_certificate layout. It
+// maps file names to the secret Ids holding the PEM bundles.
+type certificateIndex struct {
+	Layout       certificateLayout `json:"layout"`
+	Certificates map[string]string `json:"certificates"`
+}
+
+// decodeCertificateIndex returns the index stored in a main secret payload, or
+// nil if the payload uses the single layout.
+func decodeCertificateIndex(payload []byte) *certificateIndex {
+	index := &certificateIndex{}
+	if err := json.Unmarshal(payload, index); err != nil || index.Layout != certificateLayoutPerCertificate {
+		return nil
+	}
+	return index
+}
+
+// certificateSecretId returns the Id of the secret holding a single bundle in
+// the per_certificate layout. Characters not allowed in secret Ids are replaced.
+func certificateSecretId(secretId, name string) string {
+	sanitized := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, name)
+	return secretId + "-" + sanitized
+}
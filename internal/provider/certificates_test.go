@@ -0,0 +1,538 @@
+package provider
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// testClientCA returns a self-signed CA valid until notAfter and its PEM.
+func testClientCA(t *testing.T, name string, notAfter time.Time) (*x509.Certificate, *ecdsa.PrivateKey, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             notAfter.AddDate(-1, 0, 0),
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key, encodeCertificatePEM(der)
+}
+
+// testClientCRL returns the PEM of an empty CRL of the CA.
+func testClientCRL(t *testing.T, ca *x509.Certificate, key *ecdsa.PrivateKey, nextUpdate time.Time) string {
+	t.Helper()
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: nextUpdate.AddDate(0, 0, -7),
+		NextUpdate: nextUpdate,
+	}, ca, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der}))
+}
+
+func TestBuildClientCABundle(t *testing.T) {
+	now := time.Now()
+	ca, caKey, caPEM := testClientCA(t, "client CA", now.AddDate(1, 0, 0))
+	other, otherKey, _ := testClientCA(t, "other CA", now.AddDate(1, 0, 0))
+	_, _, expiredPEM := testClientCA(t, "expired CA", now.AddDate(0, 0, -1))
+	crlPEM := testClientCRL(t, ca, caKey, now.AddDate(0, 0, 7))
+
+	bundle, warnings, err := buildClientCABundle([]string{caPEM, caPEM}, []string{crlPEM}, now)
+	if err != nil {
+		t.Fatalf("buildClientCABundle: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("warnings = %v", warnings)
+	}
+	if len(bundle.certs) != 1 || len(bundle.crls) != 1 {
+		t.Errorf("bundle has %d certificates and %d CRLs, want 1 and 1", len(bundle.certs), len(bundle.crls))
+	}
+
+	bundle, warnings, err = buildClientCABundle([]string{caPEM, expiredPEM}, nil, now)
+	if err != nil {
+		t.Fatalf("buildClientCABundle with an expired CA: %v", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "expired CA") {
+		t.Errorf("warnings = %v, want the expired CA", warnings)
+	}
+	if len(bundle.certs) != 2 {
+		t.Errorf("bundle has %d certificates, want 2", len(bundle.certs))
+	}
+
+	expiredCRL := testClientCRL(t, ca, caKey, now.AddDate(0, 0, -1))
+	if _, err := parseClientCRLs([]string{expiredCRL}, []*x509.Certificate{ca}, now); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("parseClientCRLs with an expired CRL: err = %v", err)
+	}
+
+	foreignCRL := testClientCRL(t, other, otherKey, now.AddDate(0, 0, 7))
+	if _, err := parseClientCRLs([]string{foreignCRL}, []*x509.Certificate{ca}, now); err == nil || !strings.Contains(err.Error(), "not signed") {
+		t.Errorf("parseClientCRLs with a CRL of another CA: err = %v", err)
+	}
+}
+
+func TestLeafTemplateKeyUsage(t *testing.T) {
+	for _, algorithm := range keyAlgorithms {
+		key, err := generatePrivateKey(algorithm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		template, err := leafTemplate("leaf", []string{"example.test"}, certificateUsageServer, key.Public(), time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := x509.KeyUsageDigitalSignature
+		if strings.HasPrefix(string(algorithm), "RSA") {
+			want |= x509.KeyUsageKeyEncipherment
+		}
+		if template.KeyUsage != want {
+			t.Errorf("%s: key usage = %#x, want %#x", algorithm, template.KeyUsage, want)
+		}
+	}
+}
+
+// testIssueCertificate returns a certificate signed by parent, its key and its
+// PEM.
+func testIssueCertificate(t *testing.T, name string, isCA bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(0, 1, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	if isCA {
+		template.KeyUsage = x509.KeyUsageCertSign
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key, encodeCertificatePEM(der)
+}
+
+func testPrivateKeyPEM(t *testing.T, key *ecdsa.PrivateKey) string {
+	t.Helper()
+	keyPEM, err := encodePrivateKeyPEM(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keyPEM
+}
+
+func TestParseCertificateBundle(t *testing.T) {
+	root, rootKey, rootPEM := testClientCA(t, "root CA", time.Now().AddDate(1, 0, 0))
+	intermediate, intermediateKey, intermediatePEM := testIssueCertificate(t, "intermediate CA", true, root, rootKey)
+	leaf, leafKey, leafPEM := testIssueCertificate(t, "leaf.example.test", false, intermediate, intermediateKey)
+	keyPEM := testPrivateKeyPEM(t, leafKey)
+	_, otherKey, _ := testIssueCertificate(t, "other.example.test", false, intermediate, intermediateKey)
+	otherKeyPEM := testPrivateKeyPEM(t, otherKey)
+
+	bundle, err := parseCertificateBundle([]byte(keyPEM + leafPEM + intermediatePEM + rootPEM))
+	if err != nil {
+		t.Fatalf("parseCertificateBundle: %v", err)
+	}
+	if !bundle.leaf().Equal(leaf) || len(bundle.chain) != 3 {
+		t.Errorf("bundle chain starts with %q and has %d certificates, want %q and 3", bundle.leaf().Subject, len(bundle.chain), leaf.Subject)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		bundle string
+		want   string
+	}{
+		{"no key", leafPEM + intermediatePEM, "exactly one private key, found 0"},
+		{"two keys", keyPEM + keyPEM + leafPEM, "exactly one private key, found 2"},
+		{"mismatched key", otherKeyPEM + leafPEM + intermediatePEM, "does not match the leaf certificate"},
+		{"wrong order", keyPEM + leafPEM + rootPEM + intermediatePEM, "leaf-to-root order"},
+		{"no certificate", keyPEM, "does not contain a certificate"},
+		{"trailing data", keyPEM + leafPEM + "garbage", "not PEM encoded"},
+	} {
+		if _, err := parseCertificateBundle([]byte(tc.bundle)); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: err = %v, want %q", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestTLSCertificateValidateConfig(t *testing.T) {
+	ctx := context.Background()
+	root, rootKey, rootPEM := testClientCA(t, "root CA", time.Now().AddDate(1, 0, 0))
+	_, leafKey, leafPEM := testIssueCertificate(t, "leaf.example.test", false, root, rootKey)
+	certificates, diags := types.MapValueFrom(ctx, types.StringType, map[string]string{
+		"valid.pem":  testPrivateKeyPEM(t, leafKey) + leafPEM + rootPEM,
+		"broken.pem": leafPEM + rootPEM,
+	})
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	r := &TLSCertificateResource{}
+	s := testTLSCertificateSchema(r)
+	data := testTLSCertificateModel()
+	data.TLSCertificates = certificates
+	plan := tfsdk.Plan{Schema: s, Raw: tftypes.NewValue(s.Type().TerraformType(ctx), nil)}
+	if diags := plan.Set(ctx, &data); diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	resp := &resource.ValidateConfigResponse{}
+	r.ValidateConfig(ctx, resource.ValidateConfigRequest{Config: tfsdk.Config{Schema: s, Raw: plan.Raw}}, resp)
+	errs := resp.Diagnostics.Errors()
+	if len(errs) != 1 {
+		t.Fatalf("diagnostics = %v, want one error", resp.Diagnostics)
+	}
+	withPath, ok := errs[0].(diag.DiagnosticWithPath)
+	if !ok || !withPath.Path().Equal(path.Root("tls_certificates").AtMapKey("broken.pem")) {
+		t.Errorf("error %v is not attributed to tls_certificates[\"broken.pem\"]", errs[0])
+	}
+	if !strings.Contains(errs[0].Detail(), "broken.pem") {
+		t.Errorf("error detail %q does not name broken.pem", errs[0].Detail())
+	}
+}
+
+func testTLSCertificateSchema(r *TLSCertificateResource) schema.Schema {
+	schemaResp := &resource.SchemaResponse{}
+	r.Schema(context.Background(), resource.SchemaRequest{}, schemaResp)
+	return schemaResp.Schema
+}
+
+// testTLSCertificateModel returns a TLS certificate model without certificates
+// or computed values.
+func testTLSCertificateModel() TLSCertificateResourceModel {
+	return TLSCertificateResourceModel{
+		ProjectId:          types.StringValue("project"),
+		Namespace:          types.StringValue("namespace"),
+		Suffix:             types.StringValue("-tls"),
+		TLSCertificates:    types.MapNull(types.StringType),
+		PKCS12Certificates: types.MapNull(testTLSObjectType("pkcs12_certificates")),
+		DERCertificates:    types.MapNull(testTLSObjectType("der_certificates")),
+		Layout:             types.StringNull(),
+		SecretId:           types.StringNull(),
+		Fingerprints:       types.MapNull(types.StringType),
+		Metadata:           types.MapNull(certificateMetadataType),
+	}
+}
+
+func testTLSObjectType(attribute string) attr.Type {
+	return testTLSCertificateSchema(&TLSCertificateResource{}).Attributes[attribute].GetType().(types.MapType).ElemType
+}
+
+// testTLSCertificateState returns the state of a TLS certificate resource
+// storing certs.
+func testTLSCertificateState(t *testing.T, data TLSCertificateResourceModel, certs map[string]string) TLSCertificateResourceModel {
+	t.Helper()
+	stored := make(map[string][]byte, len(certs))
+	for name, contents := range certs {
+		stored[name] = []byte(contents)
+	}
+	data.SecretId = types.StringValue("namespace-tls")
+	if diags := data.setMetadata(context.Background(), stored); diags.HasError() {
+		t.Fatal(diags)
+	}
+	return data
+}
+
+func testTLSCertificateModifyPlan(t *testing.T, r *TLSCertificateResource, state, plan TLSCertificateResourceModel) *resource.ModifyPlanResponse {
+	t.Helper()
+	ctx := context.Background()
+	s := testTLSCertificateSchema(r)
+	req := resource.ModifyPlanRequest{
+		State: tfsdk.State{Schema: s, Raw: tftypes.NewValue(s.Type().TerraformType(ctx), nil)},
+		Plan:  tfsdk.Plan{Schema: s, Raw: tftypes.NewValue(s.Type().TerraformType(ctx), nil)},
+	}
+	if diags := req.State.Set(ctx, &state); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if diags := req.Plan.Set(ctx, &plan); diags.HasError() {
+		t.Fatal(diags)
+	}
+	resp := &resource.ModifyPlanResponse{Plan: req.Plan}
+	r.ModifyPlan(ctx, req, resp)
+	return resp
+}
+
+// testPlanUnknown reports whether an attribute of the plan is unknown.
+func testPlanUnknown(t *testing.T, plan tfsdk.Plan, attribute string) bool {
+	t.Helper()
+	var value types.Map
+	if diags := plan.GetAttribute(context.Background(), path.Root(attribute), &value); diags.HasError() {
+		t.Fatal(diags)
+	}
+	return value.IsUnknown()
+}
+
+func TestTLSPlaceholderRenewalPlan(t *testing.T) {
+	r := &TLSCertificateResource{certExpiryWarningDays: 30}
+	for _, tc := range []struct {
+		name     string
+		validity time.Duration
+		renew    bool
+	}{
+		{"valid", 365 * 24 * time.Hour, false},
+		{"near expiry", 10 * 24 * time.Hour, true},
+	} {
+		placeholder, err := generateSelfSignedBundle("namespace", []string{"namespace"}, defaultPlaceholderKeyAlgorithm, tc.validity)
+		if err != nil {
+			t.Fatal(err)
+		}
+		state := testTLSCertificateState(t, testTLSCertificateModel(), map[string]string{placeholderName: placeholder})
+		resp := testTLSCertificateModifyPlan(t, r, state, state)
+		if resp.Diagnostics.HasError() {
+			t.Fatalf("%s: ModifyPlan: %v", tc.name, resp.Diagnostics)
+		}
+		for _, attribute := range []string{"fingerprints", "certificate_metadata"} {
+			if got := testPlanUnknown(t, resp.Plan, attribute); got != tc.renew {
+				t.Errorf("%s: %s unknown = %t, want %t", tc.name, attribute, got, tc.renew)
+			}
+		}
+		if len(resp.Diagnostics.Warnings()) > 0 {
+			t.Errorf("%s: warnings = %v, want none", tc.name, resp.Diagnostics.Warnings())
+		}
+	}
+}
+
+func TestPKCS12AndDERToPEM(t *testing.T) {
+	root, rootKey, _ := testClientCA(t, "root CA", time.Now().AddDate(1, 0, 0))
+	intermediate, intermediateKey, _ := testIssueCertificate(t, "intermediate CA", true, root, rootKey)
+	leaf, leafKey, _ := testIssueCertificate(t, "leaf.example.test", false, intermediate, intermediateKey)
+
+	// PKCS#12 files do not order the CA certificates
+	pfx, err := pkcs12.Modern.Encode(leafKey, leaf, []*x509.Certificate{root, intermediate}, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(leafKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encode := base64.StdEncoding.EncodeToString
+
+	for name, convert := range map[string]func() (string, error){
+		"pkcs12": func() (string, error) { return pkcs12ToPEM(encode(pfx), "secret") },
+		"der": func() (string, error) {
+			return derToPEM(encode(leaf.Raw), encode(keyDER), []string{encode(root.Raw), encode(intermediate.Raw)})
+		},
+	} {
+		bundlePEM, err := convert()
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		bundle, err := parseCertificateBundle([]byte(bundlePEM))
+		if err != nil {
+			t.Errorf("%s: parseCertificateBundle: %v", name, err)
+			continue
+		}
+		want := []*x509.Certificate{leaf, intermediate, root}
+		if len(bundle.chain) != len(want) {
+			t.Errorf("%s: chain has %d certificates, want %d", name, len(bundle.chain), len(want))
+			continue
+		}
+		for i, cert := range want {
+			if !bundle.chain[i].Equal(cert) {
+				t.Errorf("%s: certificate %d is %q, want %q", name, i, bundle.chain[i].Subject, cert.Subject)
+			}
+		}
+	}
+
+	if _, err := pkcs12ToPEM(encode(pfx), "wrong"); err == nil {
+		t.Error("pkcs12ToPEM accepted a wrong password")
+	}
+	_, _, unrelatedPEM := testClientCA(t, "unrelated CA", time.Now().AddDate(1, 0, 0))
+	unrelated, _ := pem.Decode([]byte(unrelatedPEM))
+	if _, err := derToPEM(encode(leaf.Raw), encode(keyDER), []string{encode(unrelated.Bytes)}); err == nil {
+		t.Error("derToPEM accepted a chain certificate of another CA")
+	}
+}
+
+func TestTLSPlaceholderReuse(t *testing.T) {
+	ctx := context.Background()
+	data := TLSCertificateResourceModel{Namespace: types.StringValue("placeholder.example.test")}
+	now := time.Now()
+	stored, err := data.placeholder(ctx, nil, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := data.placeholder(ctx, []byte(stored), now); err != nil || got != stored {
+		t.Errorf("unchanged settings: placeholder was regenerated, err: %v", err)
+	}
+	if got, err := data.placeholder(ctx, []byte(stored), now.AddDate(0, 0, defaultPlaceholderValidityDays)); err != nil || got == stored {
+		t.Errorf("near expiry: placeholder was kept, err: %v", err)
+	}
+
+	dnsNames, diags := types.ListValueFrom(ctx, types.StringType, []string{"placeholder.example.test", "10.0.0.1"})
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	for _, tc := range []struct {
+		name   string
+		change func(*TLSPlaceholderModel)
+		keep   bool
+	}{
+		{
+			name: "defaults set explicitly",
+			change: func(p *TLSPlaceholderModel) {
+				p.CommonName = types.StringValue("placeholder.example.test")
+				p.KeyAlgorithm = types.StringValue(string(defaultPlaceholderKeyAlgorithm))
+				p.ValidityDays = types.Int32Value(defaultPlaceholderValidityDays)
+			},
+			keep: true,
+		},
+		{name: "common_name", change: func(p *TLSPlaceholderModel) { p.CommonName = types.StringValue("other.example.test") }},
+		{name: "dns_names", change: func(p *TLSPlaceholderModel) { p.DNSNames = dnsNames }},
+		{name: "key_algorithm", change: func(p *TLSPlaceholderModel) { p.KeyAlgorithm = types.StringValue(string(keyAlgorithmECDSAP384)) }},
+		{name: "validity_days", change: func(p *TLSPlaceholderModel) { p.ValidityDays = types.Int32Value(30) }},
+	} {
+		placeholder := TLSPlaceholderModel{
+			CommonName:   types.StringNull(),
+			DNSNames:     types.ListNull(types.StringType),
+			KeyAlgorithm: types.StringNull(),
+			ValidityDays: types.Int32Null(),
+		}
+		tc.change(&placeholder)
+		changed := data
+		changed.Placeholder = &placeholder
+		got, err := changed.placeholder(ctx, []byte(stored), now)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if (got == stored) != tc.keep {
+			t.Errorf("%s: placeholder kept = %t, want %t", tc.name, got == stored, tc.keep)
+		}
+	}
+}
+
+func TestTLSStoredCertificateExpiry(t *testing.T) {
+	now := time.Now()
+	expired, _, expiredPEM := testClientCA(t, "expired", now.AddDate(0, 0, -1))
+	_, _, expiringPEM := testClientCA(t, "expiring", now.AddDate(0, 0, 10))
+	_, _, validPEM := testClientCA(t, "valid", now.AddDate(1, 0, 0))
+	emptyMap, diags := types.MapValueFrom(context.Background(), types.StringType, map[string]string{})
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	for _, tc := range []struct {
+		name     string
+		contents string
+		block    bool
+		errors   int
+		warnings int
+		summary  string
+	}{
+		{name: "valid", contents: validPEM},
+		{name: "expiring", contents: expiringPEM, warnings: 1, summary: "Stored TLS certificate expires soon"},
+		{name: "expired", contents: expiredPEM, warnings: 1, summary: "Expired stored TLS certificate"},
+		{name: "expired and blocked", contents: expiredPEM, block: true, errors: 1, summary: "Expired stored TLS certificate"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &TLSCertificateResource{certExpiryWarningDays: 30, blockExpiredCertificates: tc.block}
+			// Certificates issued in the cluster are stored with an empty tls_certificates
+			data := testTLSCertificateModel()
+			data.TLSCertificates = emptyMap
+			state := testTLSCertificateState(t, data, map[string]string{"acme.pem": tc.contents})
+			resp := testTLSCertificateModifyPlan(t, r, state, state)
+			if resp.Diagnostics.ErrorsCount() != tc.errors || resp.Diagnostics.WarningsCount() != tc.warnings {
+				t.Fatalf("diagnostics = %v, want %d errors and %d warnings", resp.Diagnostics, tc.errors, tc.warnings)
+			}
+			for _, d := range resp.Diagnostics {
+				if d.Summary() != tc.summary {
+					t.Errorf("summary = %q, want %q", d.Summary(), tc.summary)
+				}
+				if !strings.Contains(d.Detail(), expired.NotAfter.UTC().Format(time.RFC3339)) && tc.contents == expiredPEM {
+					t.Errorf("detail %q does not name the expiry", d.Detail())
+				}
+			}
+		})
+	}
+}
+
+// TestTLSDriftPlan checks that certificates of der_certificates replaced outside
+// of Terraform plan both the fingerprints and the metadata Update stores.
+func TestTLSDriftPlan(t *testing.T) {
+	ctx := context.Background()
+	root, rootKey, rootPEM := testClientCA(t, "root CA", time.Now().AddDate(1, 0, 0))
+	leaf, leafKey, _ := testIssueCertificate(t, "leaf.example.test", false, root, rootKey)
+	_, driftedKey, driftedPEM := testIssueCertificate(t, "drifted.example.test", false, root, rootKey)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(leafKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encode := base64.StdEncoding.EncodeToString
+	der, diags := types.MapValueFrom(ctx, testTLSObjectType("der_certificates"), map[string]TLSDERCertificateModel{
+		"leaf.pem": {
+			Certificate: types.StringValue(encode(leaf.Raw)),
+			PrivateKey:  types.StringValue(encode(keyDER)),
+			Chain:       types.ListValueMust(types.StringType, []attr.Value{types.StringValue(encode(root.Raw))}),
+		},
+	})
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	data := testTLSCertificateModel()
+	data.DERCertificates = der
+	state := testTLSCertificateState(t, data, map[string]string{"leaf.pem": testPrivateKeyPEM(t, driftedKey) + driftedPEM + rootPEM})
+
+	r := &TLSCertificateResource{}
+	resp := testTLSCertificateModifyPlan(t, r, state, state)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("ModifyPlan: %v", resp.Diagnostics)
+	}
+	var planned TLSCertificateResourceModel
+	if diags := resp.Plan.Get(ctx, &planned); diags.HasError() {
+		t.Fatal(diags)
+	}
+	want := testTLSCertificateState(t, data, map[string]string{"leaf.pem": encodeCertificatePEM(leaf.Raw)})
+	if !planned.Fingerprints.Equal(want.Fingerprints) {
+		t.Errorf("fingerprints = %v, want %v", planned.Fingerprints, want.Fingerprints)
+	}
+	if !planned.Metadata.Equal(want.Metadata) {
+		t.Errorf("certificate_metadata = %v, want %v", planned.Metadata, want.Metadata)
+	}
+}
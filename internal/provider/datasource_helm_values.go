@@ -52,7 +52,7 @@ func (d *HelmValuesDataSource) Schema(ctx context.Context, req datasource.Schema
 								Required:            true,
 							},
 							"image_puller_secret": schema.StringAttribute{
-								MarkdownDescription: "Image puller secret key needed to pull the container images from GCR",
+								MarkdownDescription: "Image puller secret key needed to pull the container images from GCR, for example `clearblade-google_image_puller_secret.helm_value`",
 								Required:            true,
 							},
 							"enterprise_base_url": schema.StringAttribute{
@@ -0,0 +1,80 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/google/tink/go/insecurecleartextkeyset"
+	"github.com/google/tink/go/keyset"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ function.Function = &CombineMEKSharesFunction{}
+
+func NewCombineMEKSharesFunction() function.Function {
+	return &CombineMEKSharesFunction{}
+}
+
+// CombineMEKSharesFunction recombines shares created by MEKSharesResource.
+type CombineMEKSharesFunction struct{}
+
+func (f *CombineMEKSharesFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "combine_mek_shares"
+}
+
+func (f *CombineMEKSharesFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary: "Recombine MEK shares",
+		MarkdownDescription: "Reconstructs the MEK JSON keyset from at least `threshold` shares created by `clearblade-google_mek_shares`. " +
+			"Each share must already be decrypted by its custodian and base64 encoded. Only use this offline, " +
+			"as the result is the MEK in cleartext.",
+		Parameters: []function.Parameter{
+			function.ListParameter{
+				Name:                "shares",
+				ElementType:         types.StringType,
+				MarkdownDescription: "Base64 encoded decrypted shares",
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (f *CombineMEKSharesFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var encoded []string
+
+	resp.Error = function.ConcatFuncErrors(req.Arguments.Get(ctx, &encoded))
+	if resp.Error != nil {
+		return
+	}
+
+	shares := make([][]byte, len(encoded))
+	for i, e := range encoded {
+		share, err := base64.StdEncoding.DecodeString(e)
+		if err != nil {
+			resp.Error = function.NewArgumentFuncError(0, fmt.Sprintf("share %d is not valid base64: %s", i, err))
+			return
+		}
+		shares[i] = share
+	}
+	secret, err := combineShares(shares)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+	kh, err := parseMEK(secret)
+	if err != nil {
+		resp.Error = function.NewFuncError("Recombined shares are not a valid MEK, not enough or mismatched shares were given: " + err.Error())
+		return
+	}
+	var buf bytes.Buffer
+	if err := insecurecleartextkeyset.Write(kh, keyset.NewJSONWriter(&buf)); err != nil {
+		resp.Error = function.NewFuncError("Failed to serialize MEK: " + err.Error())
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Result.Set(ctx, buf.String()))
+}
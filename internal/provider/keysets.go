@@ -0,0 +1,344 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strconv"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/google/tink/go/insecurecleartextkeyset"
+	"github.com/google/tink/go/keyset"
+	commonpb "github.com/google/tink/go/proto/common_go_proto"
+	ecdsapb "github.com/google/tink/go/proto/ecdsa_go_proto"
+	ed25519pb "github.com/google/tink/go/proto/ed25519_go_proto"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"google.golang.org/protobuf/proto"
+)
+
+type keysetFormat string
+
+const (
+	keysetFormatJSON   = keysetFormat("json")
+	keysetFormatBinary = keysetFormat("binary")
+)
+
+// Ensure secretKeysetReaderWriter can be used with the Tink keyset APIs.
+var _ keyset.Reader = &secretKeysetReaderWriter{}
+var _ keyset.Writer = &secretKeysetReaderWriter{}
+
+// secretKeysetReaderWriter reads keysets from and writes keysets to a GCP secret.
+// Reads use the latest secret version and every write adds a new version.
+type secretKeysetReaderWriter struct {
+	ctx       context.Context
+	client    *secretmanager.Client
+	projectId string
+	secretId  string
+	// format is the serialization format of the stored keyset. When empty, reads
+	// detect the format from the payload and writes use JSON.
+	format keysetFormat
+}
+
+func (s *secretKeysetReaderWriter) Read() (*tinkpb.Keyset, error) {
+	r, err := s.reader()
+	if err != nil {
+		return nil, err
+	}
+	return r.Read()
+}
+
+func (s *secretKeysetReaderWriter) ReadEncrypted() (*tinkpb.EncryptedKeyset, error) {
+	r, err := s.reader()
+	if err != nil {
+		return nil, err
+	}
+	return r.ReadEncrypted()
+}
+
+func (s *secretKeysetReaderWriter) Write(ks *tinkpb.Keyset) error {
+	var buf bytes.Buffer
+	if err := s.writer(&buf).Write(ks); err != nil {
+		return err
+	}
+	return addSecretVersion(s.ctx, s.client, s.projectId, s.secretId, buf.Bytes())
+}
+
+func (s *secretKeysetReaderWriter) WriteEncrypted(ks *tinkpb.EncryptedKeyset) error {
+	var buf bytes.Buffer
+	if err := s.writer(&buf).WriteEncrypted(ks); err != nil {
+		return err
+	}
+	return addSecretVersion(s.ctx, s.client, s.projectId, s.secretId, buf.Bytes())
+}
+
+func (s *secretKeysetReaderWriter) reader() (keyset.Reader, error) {
+	resource := getSecretResourceName(s.projectId, s.secretId) + "/versions/latest"
+	secReq := &secretmanagerpb.AccessSecretVersionRequest{
+		Name: resource,
+	}
+	rval, err := s.client.AccessSecretVersion(s.ctx, secReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get keyset secret: %w", err)
+	}
+	data := rval.Payload.Data
+	format := s.format
+	if format == "" {
+		format = detectKeysetFormat(data)
+	}
+	switch format {
+	case keysetFormatJSON:
+		return keyset.NewJSONReader(bytes.NewReader(data)), nil
+	case keysetFormatBinary:
+		return keyset.NewBinaryReader(bytes.NewReader(data)), nil
+	default:
+		return nil, fmt.Errorf("unsupported keyset format %q", format)
+	}
+}
+
+// handle reads the keyset from the secret. Unlike insecurecleartextkeyset.Read
+// it keeps the Secret Manager error, so a deleted secret can be told apart.
+func (s *secretKeysetReaderWriter) handle() (*keyset.Handle, error) {
+	ks, err := s.Read()
+	if err != nil {
+		return nil, err
+	}
+	return insecurecleartextkeyset.Read(&keyset.MemReaderWriter{Keyset: ks})
+}
+
+func (s *secretKeysetReaderWriter) writer(w io.Writer) keyset.Writer {
+	if s.format == keysetFormatBinary {
+		return keyset.NewBinaryWriter(w)
+	}
+	return keyset.NewJSONWriter(w)
+}
+
+// newSecretKeyset returns the keyset reader and writer for a secret, defaulting
+// to JSON when no format is configured.
+func newSecretKeyset(ctx context.Context, client *secretmanager.Client, projectId, secretId string, format types.String) *secretKeysetReaderWriter {
+	f := keysetFormatJSON
+	if !format.IsNull() && !format.IsUnknown() {
+		f = keysetFormat(format.ValueString())
+	}
+	return &secretKeysetReaderWriter{
+		ctx:       ctx,
+		client:    client,
+		projectId: projectId,
+		secretId:  secretId,
+		format:    f,
+	}
+}
+
+// keysetConfig is the configuration of a keyset resource storing its keyset in
+// a secret. origin is what new keys are created from, like the MEK source or
+// the algorithm.
+type keysetConfig struct {
+	projectId types.String
+	namespace types.String
+	suffix    types.String
+	origin    types.String
+	format    types.String
+}
+
+func (k keysetConfig) secretId() string {
+	return getSecretId(k.namespace.ValueString(), k.suffix.ValueString())
+}
+
+// secretKeyset returns the keyset reader and writer for the keyset secret.
+func (k keysetConfig) secretKeyset(ctx context.Context, client *secretmanager.Client) *secretKeysetReaderWriter {
+	return newSecretKeyset(ctx, client, k.projectId.ValueString(), k.secretId(), k.format)
+}
+
+// storedKeyset reads the keyset from the secret. The stored format is detected,
+// as it is not known after an import.
+func (k keysetConfig) storedKeyset(ctx context.Context, client *secretmanager.Client) (*keyset.Handle, error) {
+	rw := k.secretKeyset(ctx, client)
+	rw.format = ""
+	return rw.handle()
+}
+
+// onlyKeysetFormatChanged reports whether the planned change only switches the
+// serialization format, in which case the existing keys must be kept.
+func onlyKeysetFormatChanged(state, plan keysetConfig) bool {
+	return state.projectId.Equal(plan.projectId) &&
+		state.namespace.Equal(plan.namespace) &&
+		state.suffix.Equal(plan.suffix) &&
+		state.origin.Equal(plan.origin) &&
+		!state.format.Equal(plan.format)
+}
+
+// updateSecretKeyset writes the keyset of an updated resource to its secret.
+// When only the format changed the existing keys are rewritten in the new
+// format, otherwise newHandle creates the keys.
+func updateSecretKeyset(ctx context.Context, client *secretmanager.Client, state, plan keysetConfig, newHandle func() (*keyset.Handle, error)) (*keyset.Handle, error) {
+	var kh *keyset.Handle
+	var err error
+	if onlyKeysetFormatChanged(state, plan) {
+		kh, err = state.secretKeyset(ctx, client).handle()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read keyset: %w", err)
+		}
+	} else {
+		kh, err = newHandle()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create new keyset: %w", err)
+		}
+	}
+	if err := insecurecleartextkeyset.Write(kh, plan.secretKeyset(ctx, client)); err != nil {
+		return nil, fmt.Errorf("failed to write keyset to GCP Secrets: %w", err)
+	}
+	return kh, nil
+}
+
+// rotateSecretKeyset writes the keyset of an updated resource to its secret in
+// the planned format. When origin changed a key from template becomes the
+// primary key, see rotateKeyset.
+func rotateSecretKeyset(ctx context.Context, client *secretmanager.Client, state, plan keysetConfig, template func() *tinkpb.KeyTemplate) (*keyset.Handle, error) {
+	kh, err := state.storedKeyset(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyset: %w", err)
+	}
+	if !state.origin.Equal(plan.origin) {
+		if kh, err = rotateKeyset(kh, template()); err != nil {
+			return nil, fmt.Errorf("failed to rotate keyset: %w", err)
+		}
+	}
+	if err := insecurecleartextkeyset.Write(kh, plan.secretKeyset(ctx, client)); err != nil {
+		return nil, fmt.Errorf("failed to write keyset to GCP Secrets: %w", err)
+	}
+	return kh, nil
+}
+
+// rotateKeyset adds a new primary key created from template. The existing keys
+// are kept enabled, so signatures and tags created before the rotation can still
+// be verified.
+func rotateKeyset(kh *keyset.Handle, template *tinkpb.KeyTemplate) (*keyset.Handle, error) {
+	manager := keyset.NewManagerFromHandle(kh)
+	keyId, err := manager.Add(template)
+	if err != nil {
+		return nil, err
+	}
+	if err := manager.SetPrimary(keyId); err != nil {
+		return nil, err
+	}
+	return manager.Handle()
+}
+
+// keysetAlgorithm returns the name of the template the primary key of a keyset
+// was created from, or an empty string when none matches.
+func keysetAlgorithm(kh *keyset.Handle, templates map[string]func() *tinkpb.KeyTemplate) string {
+	info := kh.KeysetInfo()
+	for _, key := range info.KeyInfo {
+		if key.KeyId != info.PrimaryKeyId {
+			continue
+		}
+		for _, name := range keyTemplateNames(templates) {
+			template := templates[name]()
+			if template.TypeUrl == key.TypeUrl && template.OutputPrefixType == key.OutputPrefixType {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// keyTemplateNames returns the sorted algorithm names of key templates, for
+// validating algorithm attributes.
+func keyTemplateNames(templates map[string]func() *tinkpb.KeyTemplate) []string {
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// detectKeysetFormat guesses the serialization format of a stored keyset. JSON
+// keysets are objects, while binary keysets start with a protobuf field tag.
+func detectKeysetFormat(data []byte) keysetFormat {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return keysetFormatJSON
+	}
+	return keysetFormatBinary
+}
+
+// publicKeysetJSON returns the public part of a private keyset as a Tink JSON keyset.
+func publicKeysetJSON(kh *keyset.Handle) (string, error) {
+	pub, err := kh.Public()
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := pub.WriteWithNoSecrets(keyset.NewJSONWriter(&buf)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y,omitempty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Kid string `json:"kid"`
+}
+
+// jwksFromKeyset returns the enabled ECDSA P-256 and Ed25519 keys of a signing
+// keyset as a JSON Web Key Set. Key ids are the Tink key ids.
+func jwksFromKeyset(kh *keyset.Handle) (string, error) {
+	pub, err := kh.Public()
+	if err != nil {
+		return "", err
+	}
+	keys := []jsonWebKey{}
+	for _, key := range insecurecleartextkeyset.KeysetMaterial(pub).Key {
+		if key.Status != tinkpb.KeyStatusType_ENABLED {
+			continue
+		}
+		jwk := jsonWebKey{
+			Use: "sig",
+			Kid: strconv.FormatUint(uint64(key.KeyId), 10),
+		}
+		switch key.KeyData.TypeUrl {
+		case "type.googleapis.com/google.crypto.tink.EcdsaPublicKey":
+			ecKey := &ecdsapb.EcdsaPublicKey{}
+			if err := proto.Unmarshal(key.KeyData.Value, ecKey); err != nil {
+				return "", err
+			}
+			if ecKey.Params.Curve != commonpb.EllipticCurveType_NIST_P256 {
+				return "", fmt.Errorf("unsupported curve %s for key %d", ecKey.Params.Curve, key.KeyId)
+			}
+			jwk.Kty = "EC"
+			jwk.Crv = "P-256"
+			jwk.Alg = "ES256"
+			// Tink may store the coordinates with a leading zero byte
+			jwk.X = base64.RawURLEncoding.EncodeToString(new(big.Int).SetBytes(ecKey.X).FillBytes(make([]byte, 32)))
+			jwk.Y = base64.RawURLEncoding.EncodeToString(new(big.Int).SetBytes(ecKey.Y).FillBytes(make([]byte, 32)))
+		case "type.googleapis.com/google.crypto.tink.Ed25519PublicKey":
+			edKey := &ed25519pb.Ed25519PublicKey{}
+			if err := proto.Unmarshal(key.KeyData.Value, edKey); err != nil {
+				return "", err
+			}
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.Alg = "EdDSA"
+			jwk.X = base64.RawURLEncoding.EncodeToString(edKey.KeyValue)
+		default:
+			return "", fmt.Errorf("unsupported key type %s for key %d", key.KeyData.TypeUrl, key.KeyId)
+		}
+		keys = append(keys, jwk)
+	}
+	jwks, err := json.Marshal(map[string][]jsonWebKey{"keys": keys})
+	if err != nil {
+		return "", err
+	}
+	return string(jwks), nil
+}
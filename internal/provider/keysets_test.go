@@ -0,0 +1,115 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/tink/go/keyset"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestOnlyKeysetFormatChanged(t *testing.T) {
+	state := keysetConfig{
+		projectId: types.StringValue("project"),
+		namespace: types.StringValue("namespace"),
+		suffix:    types.StringValue("-mek"),
+		origin:    types.StringValue("ECDSA_P256"),
+		format:    types.StringNull(),
+	}
+	for _, tc := range []struct {
+		name   string
+		change func(*keysetConfig)
+		want   bool
+	}{
+		{"format", func(k *keysetConfig) { k.format = types.StringValue("binary") }, true},
+		{"nothing", func(k *keysetConfig) {}, false},
+		{"suffix", func(k *keysetConfig) { k.suffix = types.StringValue("-other") }, false},
+		{"origin", func(k *keysetConfig) { k.origin = types.StringValue("ED25519") }, false},
+		{"format and origin", func(k *keysetConfig) {
+			k.format = types.StringValue("binary")
+			k.origin = types.StringValue("ED25519")
+		}, false},
+	} {
+		plan := state
+		tc.change(&plan)
+		if got := onlyKeysetFormatChanged(state, plan); got != tc.want {
+			t.Errorf("%s changed: onlyKeysetFormatChanged = %t, want %t", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestRotateKeyset(t *testing.T) {
+	kh, err := keyset.NewHandle(signingKeyTemplates["ECDSA_P256"]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := keysetAlgorithm(kh, signingKeyTemplates); got != "ECDSA_P256" {
+		t.Errorf("keysetAlgorithm = %q, want ECDSA_P256", got)
+	}
+	oldKid := testJWKSKids(t, kh)
+	if len(oldKid) != 1 {
+		t.Fatalf("JWKS kids = %v, want one", oldKid)
+	}
+
+	primary := kh.KeysetInfo().PrimaryKeyId
+	rotated, err := rotateKeyset(kh, signingKeyTemplates["ED25519"]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := keysetAlgorithm(rotated, signingKeyTemplates); got != "ED25519" {
+		t.Errorf("rotated keysetAlgorithm = %q, want ED25519", got)
+	}
+	if rotated.KeysetInfo().PrimaryKeyId == primary {
+		t.Error("rotation kept the primary key")
+	}
+	kids := testJWKSKids(t, rotated)
+	if len(kids) != 2 || kids[0] != oldKid[0] {
+		t.Errorf("rotated JWKS kids = %v, want %s and the new key", kids, oldKid[0])
+	}
+}
+
+func testJWKSKids(t *testing.T, kh *keyset.Handle) []string {
+	t.Helper()
+	jwks, err := jwksFromKeyset(kh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal([]byte(jwks), &set); err != nil {
+		t.Fatal(err)
+	}
+	kids := make([]string, 0, len(set.Keys))
+	for _, key := range set.Keys {
+		kids = append(kids, key.Kid)
+	}
+	return kids
+}
+
+// TestKeysetResourceModels checks that the models embedding the shared keyset
+// attributes match the resource schemas.
+func TestKeysetResourceModels(t *testing.T) {
+	ctx := context.Background()
+	signing := NewSigningKeysetResource().(*SigningKeysetResource)
+	mac := NewMACKeysetResource().(*MACKeysetResource)
+	for _, tc := range []struct {
+		resource resource.Resource
+		model    keysetModel
+	}{
+		{signing, signing.newModel()},
+		{mac, mac.newModel()},
+	} {
+		schemaResp := &resource.SchemaResponse{}
+		tc.resource.Schema(ctx, resource.SchemaRequest{}, schemaResp)
+		s := schemaResp.Schema
+		state := tfsdk.State{Schema: s, Raw: tftypes.NewValue(s.Type().TerraformType(ctx), nil)}
+		if diags := state.Set(ctx, tc.model); diags.HasError() {
+			t.Errorf("%T: %v", tc.resource, diags)
+		}
+	}
+}
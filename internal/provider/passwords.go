@@ -0,0 +1,242 @@
+package provider
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+type hashAlgorithm string
+
+const (
+	hashAlgorithmSHA512      = hashAlgorithm("sha512")
+	hashAlgorithmBcrypt      = hashAlgorithm("bcrypt")
+	hashAlgorithmScrypt      = hashAlgorithm("scrypt")
+	hashAlgorithmArgon2id    = hashAlgorithm("argon2id")
+	hashAlgorithmPBKDF2      = hashAlgorithm("pbkdf2")
+	hashAlgorithmSCRAMSHA256 = hashAlgorithm("scram-sha-256")
+)
+
+// hashAlgorithms are the names of the supported hash algorithms.
+var hashAlgorithms = []string{
+	string(hashAlgorithmSHA512),
+	string(hashAlgorithmBcrypt),
+	string(hashAlgorithmScrypt),
+	string(hashAlgorithmArgon2id),
+	string(hashAlgorithmPBKDF2),
+	string(hashAlgorithmSCRAMSHA256),
+}
+
+const (
+	saltSize = 16
+
+	bcryptCost = bcrypt.DefaultCost
+
+	scryptLogN    = 15
+	scryptR       = 8
+	scryptP       = 1
+	scryptKeySize = 32
+
+	argon2Memory  = 64 * 1024
+	argon2Time    = 3
+	argon2Threads = 4
+	argon2KeySize = 32
+
+	pbkdf2Iterations = 600000
+	pbkdf2KeySize    = 32
+
+	// scramIterations is the PostgreSQL default.
+	scramIterations = 4096
+)
+
+// hashParamLimits are the accepted ranges of the verifier parameters. They
+// bound the cost of verifying a hash read from state, which happens on every
+// refresh, and reject values the key derivation functions cannot handle.
+var hashParamLimits = map[hashAlgorithm]map[string][2]int{
+	// scrypt uses 128 * 2^ln * r bytes of memory, 1 GiB at most
+	hashAlgorithmScrypt: {"ln": {1, 20}, "r": {1, 32}, "p": {1, 16}},
+	// m is in KiB, 1 GiB at most; p is a uint8 and must not be 0
+	hashAlgorithmArgon2id:    {"m": {8, 1 << 20}, "t": {1, 16}, "p": {1, 255}},
+	hashAlgorithmPBKDF2:      {"i": {1, 10000000}},
+	hashAlgorithmSCRAMSHA256: {"i": {1, 10000000}},
+}
+
+// hashPasswordWith returns the verifier of a password in the format commonly
+// used for the algorithm:
+//
+//	sha512:        URL base64 of the unsalted digest
+//	bcrypt:        $2a$10$...
+//	scrypt:        $scrypt$ln=15,r=8,p=1$<salt>$<hash>
+//	argon2id:      $argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>
+//	pbkdf2:        $pbkdf2-sha256$600000$<salt>$<hash>
+//	scram-sha-256: SCRAM-SHA-256$4096:<salt>$<StoredKey>:<ServerKey>
+func hashPasswordWith(algorithm hashAlgorithm, password string) (string, error) {
+	if algorithm == hashAlgorithmSHA512 {
+		return hashPassword(password), nil
+	}
+	if algorithm == hashAlgorithmBcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
+		return string(hash), err
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	return saltedHash(algorithm, password, salt, nil)
+}
+
+// saltedHash computes the verifier of a salted algorithm. params are the
+// parameters parsed from an existing verifier, the defaults are used when nil.
+func saltedHash(algorithm hashAlgorithm, password string, salt []byte, params map[string]int) (string, error) {
+	param := func(name string, value int) int {
+		if v, ok := params[name]; ok {
+			return v
+		}
+		return value
+	}
+	b64 := base64.RawStdEncoding.EncodeToString
+
+	switch algorithm {
+	case hashAlgorithmScrypt:
+		ln, r, p := param("ln", scryptLogN), param("r", scryptR), param("p", scryptP)
+		key, err := scrypt.Key([]byte(password), salt, 1<<ln, r, p, scryptKeySize)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("$scrypt$ln=%d,r=%d,p=%d$%s$%s", ln, r, p, b64(salt), b64(key)), nil
+	case hashAlgorithmArgon2id:
+		m, t, p := param("m", argon2Memory), param("t", argon2Time), param("p", argon2Threads)
+		key := argon2.IDKey([]byte(password), salt, uint32(t), uint32(m), uint8(p), argon2KeySize)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, m, t, p, b64(salt), b64(key)), nil
+	case hashAlgorithmPBKDF2:
+		i := param("i", pbkdf2Iterations)
+		key := pbkdf2.Key([]byte(password), salt, i, pbkdf2KeySize, sha256.New)
+		return fmt.Sprintf("$pbkdf2-sha256$%d$%s$%s", i, b64(salt), b64(key)), nil
+	case hashAlgorithmSCRAMSHA256:
+		i := param("i", scramIterations)
+		salted := pbkdf2.Key([]byte(password), salt, i, sha256.Size, sha256.New)
+		clientKey := hmacSHA256(salted, "Client Key")
+		storedKey := sha256.Sum256(clientKey)
+		serverKey := hmacSHA256(salted, "Server Key")
+		std := base64.StdEncoding.EncodeToString
+		return fmt.Sprintf("SCRAM-SHA-256$%d:%s$%s:%s", i, std(salt), std(storedKey[:]), std(serverKey)), nil
+	}
+	return "", fmt.Errorf("unsupported hash algorithm %q", algorithm)
+}
+
+func hmacSHA256(key []byte, message string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
+
+// verifyPasswordHash reports whether hash is a verifier of password created by
+// hashPasswordWith.
+func verifyPasswordHash(algorithm hashAlgorithm, password, hash string) bool {
+	switch algorithm {
+	case hashAlgorithmSHA512:
+		return subtle.ConstantTimeCompare([]byte(hashPassword(password)), []byte(hash)) == 1
+	case hashAlgorithmBcrypt:
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	}
+	salt, params, err := parseSaltedHash(algorithm, hash)
+	if err != nil {
+		return false
+	}
+	computed, err := saltedHash(algorithm, password, salt, params)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(hash)) == 1
+}
+
+// parseSaltedHash extracts the salt and parameters of a verifier. Parameters
+// outside of hashParamLimits are rejected.
+func parseSaltedHash(algorithm hashAlgorithm, hash string) ([]byte, map[string]int, error) {
+	salt, params, err := parseSaltedHashFields(algorithm, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	limits := hashParamLimits[algorithm]
+	for name, value := range params {
+		limit, ok := limits[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown %s hash parameter %q", algorithm, name)
+		}
+		if value < limit[0] || value > limit[1] {
+			return nil, nil, fmt.Errorf("%s hash parameter %s=%d is not between %d and %d", algorithm, name, value, limit[0], limit[1])
+		}
+	}
+	return salt, params, nil
+}
+
+func parseSaltedHashFields(algorithm hashAlgorithm, hash string) ([]byte, map[string]int, error) {
+	params := map[string]int{}
+	var salt string
+	switch algorithm {
+	case hashAlgorithmScrypt, hashAlgorithmArgon2id:
+		// $scrypt$ln=15,r=8,p=1$salt$hash or $argon2id$v=19$m=65536,t=3,p=4$salt$hash
+		fields := strings.Split(hash, "$")
+		if len(fields) < 5 || fields[1] != string(algorithm) {
+			return nil, nil, fmt.Errorf("invalid %s hash", algorithm)
+		}
+		for _, kv := range strings.Split(fields[len(fields)-3], ",") {
+			name, value, _ := strings.Cut(kv, "=")
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid %s hash parameter %q", algorithm, kv)
+			}
+			params[name] = n
+		}
+		salt = fields[len(fields)-2]
+	case hashAlgorithmPBKDF2:
+		// $pbkdf2-sha256$i$salt$hash
+		fields := strings.Split(hash, "$")
+		if len(fields) != 5 || fields[1] != "pbkdf2-sha256" {
+			return nil, nil, fmt.Errorf("invalid %s hash", algorithm)
+		}
+		i, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid %s hash iterations", algorithm)
+		}
+		params["i"] = i
+		salt = fields[3]
+	case hashAlgorithmSCRAMSHA256:
+		// SCRAM-SHA-256$i:salt$StoredKey:ServerKey
+		method, rest, _ := strings.Cut(hash, "$")
+		iterSalt, _, _ := strings.Cut(rest, "$")
+		iter, saltStd, ok := strings.Cut(iterSalt, ":")
+		if method != "SCRAM-SHA-256" || !ok {
+			return nil, nil, fmt.Errorf("invalid %s hash", algorithm)
+		}
+		i, err := strconv.Atoi(iter)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid %s hash iterations", algorithm)
+		}
+		params["i"] = i
+		decoded, err := base64.StdEncoding.DecodeString(saltStd)
+		return decoded, params, err
+	default:
+		return nil, nil, fmt.Errorf("unsupported hash algorithm %q", algorithm)
+	}
+	decoded, err := base64.RawStdEncoding.DecodeString(salt)
+	return decoded, params, err
+}
+
+func hashPassword(password string) string {
+	hasher := sha512.New()
+	hasher.Write([]byte(password))
+	sha := base64.URLEncoding.EncodeToString(hasher.Sum(nil))
+	return sha
+}
@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestVerifyPasswordHashKnownAnswers(t *testing.T) {
+	for _, tc := range []struct {
+		algorithm hashAlgorithm
+		password  string
+		hash      string
+	}{
+		// RFC 7677 section 3, stored as a PostgreSQL verifier.
+		{hashAlgorithmSCRAMSHA256, "pencil", "SCRAM-SHA-256$4096:W22ZaJ0SNY7soEsUEjb6gQ==$WG5d8oPm3OtcPnkdi4Uo7BkeZkBFzpcXkuLmtbsT4qY=:wfPLwcE6nTWhTAmQ7tl2KeoiWGPlZqQxSrmfPwDl2dU="},
+		// Argon2 reference implementation test vector.
+		{hashAlgorithmArgon2id, "password", "$argon2id$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc"},
+		// RFC 7914 section 12, truncated to the 32 byte key size.
+		{hashAlgorithmScrypt, "password", "$scrypt$ln=10,r=8,p=16$TmFDbA$/bq+HJ00cgB4VucZDQHp/nxq18vII3gw53N2Y0s3MWI"},
+		// RFC 7914 section 11, truncated to the 32 byte key size.
+		{hashAlgorithmPBKDF2, "passwd", "$pbkdf2-sha256$1$c2FsdA$VawEblbjCJ/sFpHCJUS2BflBhSFt3gRl5oudV8INrLw"},
+	} {
+		if !verifyPasswordHash(tc.algorithm, tc.password, tc.hash) {
+			t.Errorf("%s: known hash does not verify", tc.algorithm)
+		}
+		if verifyPasswordHash(tc.algorithm, tc.password+"x", tc.hash) {
+			t.Errorf("%s: wrong password verifies", tc.algorithm)
+		}
+		salt, params, err := parseSaltedHash(tc.algorithm, tc.hash)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.algorithm, err)
+		}
+		if hash, err := saltedHash(tc.algorithm, tc.password, salt, params); err != nil || hash != tc.hash {
+			t.Errorf("%s: saltedHash = %q, %v, want %q", tc.algorithm, hash, err, tc.hash)
+		}
+	}
+}
+
+// TestSCRAMSHA256Exchange checks the verifier authenticates the RFC 7677
+// example exchange, the way PostgreSQL uses it.
+func TestSCRAMSHA256Exchange(t *testing.T) {
+	salt, params, err := parseSaltedHash(hashAlgorithmSCRAMSHA256, "SCRAM-SHA-256$4096:W22ZaJ0SNY7soEsUEjb6gQ==$x:y")
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := saltedHash(hashAlgorithmSCRAMSHA256, "pencil", salt, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, keys, _ := strings.Cut(strings.SplitN(verifier, "$", 2)[1], "$")
+	storedKeyB64, serverKeyB64, _ := strings.Cut(keys, ":")
+	storedKey, _ := base64.StdEncoding.DecodeString(storedKeyB64)
+	serverKey, _ := base64.StdEncoding.DecodeString(serverKeyB64)
+
+	authMessage := "n=user,r=rOprNGfwEbeRWgbNEkqO," +
+		"r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096," +
+		"c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0"
+	proof, _ := base64.StdEncoding.DecodeString("dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ=")
+
+	clientSignature := hmacSHA256(storedKey, authMessage)
+	clientKey := make([]byte, len(proof))
+	for i := range proof {
+		clientKey[i] = proof[i] ^ clientSignature[i]
+	}
+	if sum := sha256.Sum256(clientKey); !hmac.Equal(sum[:], storedKey) {
+		t.Error("client proof does not match StoredKey")
+	}
+	if got := base64.StdEncoding.EncodeToString(hmacSHA256(serverKey, authMessage)); got != "6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4=" {
+		t.Errorf("server signature = %s", got)
+	}
+}
+
+func TestHashPasswordWithRoundTrip(t *testing.T) {
+	for _, algorithm := range hashAlgorithms {
+		hash, err := hashPasswordWith(hashAlgorithm(algorithm), "secret")
+		if err != nil {
+			t.Fatalf("%s: %v", algorithm, err)
+		}
+		if !verifyPasswordHash(hashAlgorithm(algorithm), "secret", hash) {
+			t.Errorf("%s: hash %q does not verify", algorithm, hash)
+		}
+	}
+}
+
+func TestParseSaltedHashRejectsInvalidParameters(t *testing.T) {
+	for _, tc := range []struct {
+		algorithm hashAlgorithm
+		hash      string
+	}{
+		{hashAlgorithmArgon2id, "$argon2id$v=19$m=65536,t=2,p=0$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc"},
+		{hashAlgorithmArgon2id, "$argon2id$v=19$m=65536,t=0,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc"},
+		{hashAlgorithmArgon2id, "$argon2id$v=19$m=4294967296,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc"},
+		{hashAlgorithmArgon2id, "$argon2id$v=19$m=65536,t=2,p=256$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc"},
+		{hashAlgorithmArgon2id, "$argon2id$v=19$m=65536,t=2,p=1,x=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc"},
+		{hashAlgorithmScrypt, "$scrypt$ln=0,r=8,p=1$TmFDbA$/bq+HJ00cgB4VucZDQHp/nxq18vII3gw53N2Y0s3MWI"},
+		{hashAlgorithmScrypt, "$scrypt$ln=64,r=8,p=1$TmFDbA$/bq+HJ00cgB4VucZDQHp/nxq18vII3gw53N2Y0s3MWI"},
+		{hashAlgorithmScrypt, "$scrypt$ln=10,r=-1,p=1$TmFDbA$/bq+HJ00cgB4VucZDQHp/nxq18vII3gw53N2Y0s3MWI"},
+		{hashAlgorithmPBKDF2, "$pbkdf2-sha256$0$c2FsdA$VawEblbjCJ/sFpHCJUS2BflBhSFt3gRl5oudV8INrLw"},
+		{hashAlgorithmSCRAMSHA256, "SCRAM-SHA-256$-1:W22ZaJ0SNY7soEsUEjb6gQ==$x:y"},
+	} {
+		if _, _, err := parseSaltedHash(tc.algorithm, tc.hash); err == nil {
+			t.Errorf("parseSaltedHash(%q) accepted invalid parameters", tc.hash)
+		}
+		if verifyPasswordHash(tc.algorithm, "password", tc.hash) {
+			t.Errorf("verifyPasswordHash(%q) = true", tc.hash)
+		}
+	}
+}
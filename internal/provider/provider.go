@@ -21,10 +21,25 @@ type ClearBladeGoogleProvider struct{}
 
 // ClearBladeGoogleProviderModel describes the provider data model.
 type ClearBladeGoogleProviderModel struct {
-	Project     types.String `tfsdk:"project"`
-	AccessToken types.String `tfsdk:"access_token"`
+	Project                  types.String `tfsdk:"project"`
+	AccessToken              types.String `tfsdk:"access_token"`
+	CertExpiryWarningDays    types.Int32  `tfsdk:"cert_expiry_warning_days"`
+	BlockExpiredCertificates types.Bool   `tfsdk:"block_expired_certificates"`
 }
 
+// ClearBladeGoogleProviderData is passed to resources when they are configured.
+type ClearBladeGoogleProviderData struct {
+	client *secretmanager.Client
+	// certExpiryWarningDays is the number of days before expiry at which TLS
+	// certificates are warned about during plan. Zero disables the warnings.
+	certExpiryWarningDays int32
+	// blockExpiredCertificates fails plans storing expired TLS certificates
+	// instead of warning about them.
+	blockExpiredCertificates bool
+}
+
+const defaultCertExpiryWarningDays = 30
+
 func (o *ClearBladeGoogleProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
 	resp.TypeName = "clearblade-google"
 }
@@ -38,6 +53,14 @@ func (o *ClearBladeGoogleProvider) Schema(ctx context.Context, req provider.Sche
 			"access_token": schema.StringAttribute{
 				Optional: true,
 			},
+			"cert_expiry_warning_days": schema.Int32Attribute{
+				MarkdownDescription: "Warn during plan about TLS certificates expiring within this many days. Defaults to 30, 0 disables the warnings",
+				Optional:            true,
+			},
+			"block_expired_certificates": schema.BoolAttribute{
+				MarkdownDescription: "Fail plans which store already expired TLS certificates instead of warning. Defaults to true",
+				Optional:            true,
+			},
 		},
 	}
 }
@@ -63,15 +86,37 @@ func (o *ClearBladeGoogleProvider) Configure(ctx context.Context, req provider.C
 		resp.Diagnostics.AddError("Failed to create secret mgr client", err.Error())
 		return
 	}
-	resp.DataSourceData = client
-	resp.ResourceData = client
+	providerData := &ClearBladeGoogleProviderData{
+		client:                   client,
+		certExpiryWarningDays:    defaultCertExpiryWarningDays,
+		blockExpiredCertificates: true,
+	}
+	if !data.CertExpiryWarningDays.IsNull() {
+		providerData.certExpiryWarningDays = data.CertExpiryWarningDays.ValueInt32()
+	}
+	if !data.BlockExpiredCertificates.IsNull() {
+		providerData.blockExpiredCertificates = data.BlockExpiredCertificates.ValueBool()
+	}
+	resp.DataSourceData = providerData
+	resp.ResourceData = providerData
 }
 
 func (o *ClearBladeGoogleProvider) Resources(ctx context.Context) []func() resource.Resource {
 	return []func() resource.Resource{
 		NewMEKResource,
+		NewMEKEscrowResource,
+		NewMEKSharesResource,
 		NewRandomStringResource,
 		NewTLSCertificateResource,
+		NewSigningKeysetResource,
+		NewMACKeysetResource,
+		NewCAResource,
+		NewIssuedCertificateResource,
+		NewClientCABundleResource,
+		NewACMECertificateResource,
+		NewDatabaseCredentialsResource,
+		NewImagePullerSecretResource,
+		NewSecretResource,
 	}
 }
 
@@ -82,7 +127,9 @@ func (o *ClearBladeGoogleProvider) DataSources(ctx context.Context) []func() dat
 }
 
 func (o *ClearBladeGoogleProvider) Functions(ctx context.Context) []func() function.Function {
-	return []func() function.Function{}
+	return []func() function.Function{
+		NewCombineMEKSharesFunction,
+	}
 }
 
 func New() func() provider.Provider {
@@ -0,0 +1,528 @@
+package provider
+
+import (
+	"context"
+	"crypto"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &ACMECertificateResource{}
+var _ resource.ResourceWithValidateConfig = &ACMECertificateResource{}
+var _ resource.ResourceWithModifyPlan = &ACMECertificateResource{}
+
+const (
+	defaultACMERenewalDays         = 30
+	defaultACMEHTTP01ListenAddress = ":80"
+)
+
+func NewACMECertificateResource() resource.Resource {
+	return &ACMECertificateResource{}
+}
+
+// ACMECertificateResource defines the resource implementation.
+type ACMECertificateResource struct {
+	client *secretmanager.Client
+}
+
+// ACMECertificateResourceModel describes the resource data model.
+type ACMECertificateResourceModel struct {
+	ProjectId      types.String     `tfsdk:"project_id"`
+	Namespace      types.String     `tfsdk:"namespace"`
+	Suffix         types.String     `tfsdk:"suffix"`
+	DirectoryURL   types.String     `tfsdk:"directory_url"`
+	DirectoryCAPEM types.String     `tfsdk:"directory_ca_pem"`
+	Email          types.String     `tfsdk:"email"`
+	EAB            *ACMEEABModel    `tfsdk:"eab"`
+	Domains        types.List       `tfsdk:"domains"`
+	KeyAlgorithm   types.String     `tfsdk:"key_algorithm"`
+	FileName       types.String     `tfsdk:"file_name"`
+	RenewalDays    types.Int32      `tfsdk:"renewal_days"`
+	HTTP01         *ACMEHTTP01Model `tfsdk:"http01"`
+	DNS01          *ACMEDNS01Model  `tfsdk:"dns01"`
+	SecretId       types.String     `tfsdk:"secret_id"`
+	AccountKeyPEM  types.String     `tfsdk:"account_key_pem"`
+	CertificatePEM types.String     `tfsdk:"certificate_pem"`
+	CAChainPEM     types.String     `tfsdk:"ca_chain_pem"`
+	NotAfter       types.String     `tfsdk:"not_after"`
+}
+
+// ACMEEABModel describes the external account binding of the ACME account.
+type ACMEEABModel struct {
+	KeyId   types.String `tfsdk:"key_id"`
+	HMACKey types.String `tfsdk:"hmac_key"`
+}
+
+// ACMEHTTP01Model describes the built-in HTTP-01 challenge server.
+type ACMEHTTP01Model struct {
+	ListenAddress types.String `tfsdk:"listen_address"`
+}
+
+// ACMEDNS01Model describes the DNS-01 challenge hook commands.
+type ACMEDNS01Model struct {
+	PresentCommand types.String `tfsdk:"present_command"`
+	CleanupCommand types.String `tfsdk:"cleanup_command"`
+}
+
+func (a *ACMECertificateResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_acme_certificate"
+}
+
+func (a *ACMECertificateResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Certificate issued by an ACME directory, stored in GCP Secrets in the same layout as " +
+			"`clearblade-google_tls_certificate`. The certificate is renewed during apply once it expires within `renewal_days`",
+
+		Attributes: map[string]schema.Attribute{
+			"project_id": schema.StringAttribute{
+				MarkdownDescription: "GCP project Id for storing the certificate",
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"namespace": schema.StringAttribute{
+				MarkdownDescription: "Instance namespace",
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"suffix": schema.StringAttribute{
+				MarkdownDescription: "Secret Id suffix",
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"directory_url": schema.StringAttribute{
+				MarkdownDescription: "ACME directory URL, for example `https://acme-v02.api.letsencrypt.org/directory`",
+				Required:            true,
+			},
+			"directory_ca_pem": schema.StringAttribute{
+				MarkdownDescription: "PEM encoded CA certificates trusted for the directory in addition to the system roots, for example for Pebble",
+				Optional:            true,
+			},
+			"email": schema.StringAttribute{
+				MarkdownDescription: "Contact email of the ACME account",
+				Optional:            true,
+			},
+			"eab": schema.SingleNestedAttribute{
+				MarkdownDescription: "External account binding required by some directories",
+				Optional:            true,
+				Attributes: map[string]schema.Attribute{
+					"key_id": schema.StringAttribute{
+						MarkdownDescription: "EAB key Id",
+						Required:            true,
+					},
+					"hmac_key": schema.StringAttribute{
+						MarkdownDescription: "Base64url encoded EAB HMAC key",
+						Required:            true,
+						Sensitive:           true,
+					},
+				},
+			},
+			"domains": schema.ListAttribute{
+				MarkdownDescription: "Domains of the certificate. The first domain is the common name",
+				ElementType:         types.StringType,
+				Required:            true,
+			},
+			"key_algorithm": schema.StringAttribute{
+				MarkdownDescription: "Private key algorithm, one of `RSA2048`, `RSA4096`, `ECDSA_P256` (default) or `ECDSA_P384`",
+				Optional:            true,
+			},
+			"file_name": schema.StringAttribute{
+				MarkdownDescription: "File name of the bundle in the secret. Defaults to `<first domain>.pem`",
+				Optional:            true,
+			},
+			"renewal_days": schema.Int32Attribute{
+				MarkdownDescription: "Renew the certificate when it expires within this many days. Defaults to 30",
+				Optional:            true,
+			},
+			"http01": schema.SingleNestedAttribute{
+				MarkdownDescription: "Answer HTTP-01 challenges with a built-in server. This is the default when `dns01` is not set",
+				Optional:            true,
+				Attributes: map[string]schema.Attribute{
+					"listen_address": schema.StringAttribute{
+						MarkdownDescription: "Address the challenge server listens on. Defaults to `:80`",
+						Optional:            true,
+					},
+				},
+			},
+			"dns01": schema.SingleNestedAttribute{
+				MarkdownDescription: "Answer DNS-01 challenges with shell commands. The commands get the `ACME_DOMAIN`, " +
+					"`ACME_RECORD_NAME` and `ACME_RECORD_VALUE` environment variables and the present command must wait until the TXT record is visible",
+				Optional: true,
+				Attributes: map[string]schema.Attribute{
+					"present_command": schema.StringAttribute{
+						MarkdownDescription: "Command creating the TXT record",
+						Required:            true,
+					},
+					"cleanup_command": schema.StringAttribute{
+						MarkdownDescription: "Command removing the TXT record",
+						Optional:            true,
+					},
+				},
+			},
+			"secret_id": schema.StringAttribute{
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"account_key_pem": schema.StringAttribute{
+				MarkdownDescription: "PEM encoded ACME account key, reused for renewals",
+				Computed:            true,
+				Sensitive:           true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"certificate_pem": schema.StringAttribute{
+				MarkdownDescription: "PEM encoded certificate",
+				Computed:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"ca_chain_pem": schema.StringAttribute{
+				MarkdownDescription: "PEM encoded CA certificates in leaf-to-root order",
+				Computed:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"not_after": schema.StringAttribute{
+				MarkdownDescription: "RFC 3339 expiry timestamp of the certificate",
+				Computed:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+		},
+	}
+}
+
+func (a *ACMECertificateResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*ClearBladeGoogleProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *ClearBladeGoogleProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	a.client = providerData.client
+}
+
+func (a *ACMECertificateResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data ACMECertificateResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	secretId := getSecretId(data.Namespace.ValueString(), data.Suffix.ValueString())
+	if err := createSecret(ctx, a.client, data.ProjectId.ValueString(), secretId); err != nil {
+		resp.Diagnostics.AddError("Failed to create secret", err.Error())
+		return
+	}
+	data.SecretId = types.StringValue(secretId)
+	fileName, diags := data.fileName(ctx)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	warnings, err := a.issue(ctx, &data, fileName)
+	for _, warning := range warnings {
+		resp.Diagnostics.AddWarning("Failed to clean up ACME challenge", warning)
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to issue ACME certificate", err.Error())
+		return
+	}
+	tflog.Trace(ctx, "issued and stored ACME certificate to GCP secrets")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (a *ACMECertificateResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data ACMECertificateResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	secretId := getSecretId(data.Namespace.ValueString(), data.Suffix.ValueString())
+	payload, err := getLatestSecretVersion(ctx, a.client, data.ProjectId.ValueString(), secretId)
+	if err != nil {
+		if isSecretNotFound(err) {
+			tflog.Warn(ctx, "ACME certificate secret not found, removing from state")
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.AddError("Failed to get ACME certificate secret", err.Error())
+		return
+	}
+	data.SecretId = types.StringValue(secretId)
+	fileName, diags := data.fileName(ctx)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if err := data.setCertificate(payload, fileName); err != nil {
+		resp.Diagnostics.AddError("Failed to parse ACME certificate", err.Error())
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (a *ACMECertificateResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data ACMECertificateResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// ModifyPlan leaves the certificate unknown when it has to be issued again,
+	// other changes only affect future renewals.
+	if data.CertificatePEM.IsUnknown() {
+		fileName, diags := data.fileName(ctx)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		warnings, err := a.issue(ctx, &data, fileName)
+		for _, warning := range warnings {
+			resp.Diagnostics.AddWarning("Failed to clean up ACME challenge", warning)
+		}
+		if err != nil {
+			resp.Diagnostics.AddError("Failed to renew ACME certificate", err.Error())
+			return
+		}
+		tflog.Trace(ctx, "renewed and stored ACME certificate to GCP secrets")
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (a *ACMECertificateResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data ACMECertificateResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	secretId := getSecretId(data.Namespace.ValueString(), data.Suffix.ValueString())
+	resource := getSecretResourceName(data.ProjectId.ValueString(), secretId)
+	delReq := &secretmanagerpb.DeleteSecretRequest{
+		Name: resource,
+	}
+	if err := a.client.DeleteSecret(ctx, delReq); err != nil {
+		resp.Diagnostics.AddError("Failed to delete ACME certificate", err.Error())
+		return
+	}
+}
+
+func (a *ACMECertificateResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data ACMECertificateResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.HTTP01 != nil && data.DNS01 != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("dns01"), "Conflicting challenge attributes", "only one of http01 and dns01 can be set")
+	}
+	if domains, known := knownStrings(ctx, data.Domains, &resp.Diagnostics); known && len(domains) == 0 {
+		resp.Diagnostics.AddAttributeError(path.Root("domains"), "Invalid domains attribute", "at least one domain is required")
+	}
+	if err := validateKeyAlgorithm(data.KeyAlgorithm); err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("key_algorithm"), "Invalid key_algorithm attribute", err.Error())
+	}
+	if !data.RenewalDays.IsNull() && !data.RenewalDays.IsUnknown() && data.RenewalDays.ValueInt32() < 0 {
+		resp.Diagnostics.AddAttributeError(path.Root("renewal_days"), "Invalid renewal_days attribute", "renewal_days must not be negative")
+	}
+}
+
+func (a *ACMECertificateResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to renew when the resource is created or destroyed.
+	if req.Plan.Raw.IsNull() || req.State.Raw.IsNull() {
+		return
+	}
+
+	var plan, state ACMECertificateResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	planFileName, diags := plan.fileName(ctx)
+	resp.Diagnostics.Append(diags...)
+	stateFileName, diags := state.fileName(ctx)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	reissue := !plan.Domains.Equal(state.Domains) || !plan.KeyAlgorithm.Equal(state.KeyAlgorithm) ||
+		!plan.DirectoryURL.Equal(state.DirectoryURL) || planFileName != stateFileName
+	if notAfter, err := time.Parse(time.RFC3339, state.NotAfter.ValueString()); err != nil {
+		reissue = true
+	} else if time.Now().AddDate(0, 0, int(plan.renewalDays())).After(notAfter) {
+		resp.Diagnostics.AddWarning("ACME certificate will be renewed",
+			fmt.Sprintf("%s expires on %s", state.SecretId.ValueString(), state.NotAfter.ValueString()))
+		reissue = true
+	}
+	if !reissue {
+		return
+	}
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("certificate_pem"), types.StringUnknown())...)
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("ca_chain_pem"), types.StringUnknown())...)
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("not_after"), types.StringUnknown())...)
+}
+
+// issue obtains a new certificate and stores it in the secret as fileName. The
+// returned warnings are challenge cleanup failures.
+func (a *ACMECertificateResource) issue(ctx context.Context, data *ACMECertificateResourceModel, fileName string) ([]string, error) {
+	var domains []string
+	if diags := data.Domains.ElementsAs(ctx, &domains, false); diags.HasError() {
+		return nil, fmt.Errorf("failed to read domains")
+	}
+	accountKey, err := data.accountKey()
+	if err != nil {
+		return nil, err
+	}
+	issuer := &acmeIssuer{
+		directoryURL:      data.DirectoryURL.ValueString(),
+		directoryCAPEM:    data.DirectoryCAPEM.ValueString(),
+		accountKey:        accountKey,
+		email:             data.Email.ValueString(),
+		httpListenAddress: defaultACMEHTTP01ListenAddress,
+	}
+	if data.EAB != nil {
+		issuer.eabKeyID = data.EAB.KeyId.ValueString()
+		issuer.eabHMACKey = data.EAB.HMACKey.ValueString()
+	}
+	if data.HTTP01 != nil && !data.HTTP01.ListenAddress.IsNull() {
+		issuer.httpListenAddress = data.HTTP01.ListenAddress.ValueString()
+	}
+	if data.DNS01 != nil {
+		issuer.dnsPresentCommand = data.DNS01.PresentCommand.ValueString()
+		issuer.dnsCleanupCommand = data.DNS01.CleanupCommand.ValueString()
+	}
+	algorithm := keyAlgorithmECDSAP256
+	if !data.KeyAlgorithm.IsNull() {
+		algorithm = keyAlgorithm(data.KeyAlgorithm.ValueString())
+	}
+
+	bundle, warnings, err := issuer.issue(ctx, domains, algorithm)
+	if err != nil {
+		return warnings, err
+	}
+	payload, err := encodeCertificateSecret(map[string]string{fileName: bundle})
+	if err != nil {
+		return warnings, err
+	}
+	if err := addSecretVersion(ctx, a.client, data.ProjectId.ValueString(), data.SecretId.ValueString(), payload); err != nil {
+		return warnings, fmt.Errorf("failed to add certificate to secret: %w", err)
+	}
+	return warnings, data.setCertificate(payload, fileName)
+}
+
+// accountKey returns the ACME account key from the state, generating one for new
+// resources.
+func (d *ACMECertificateResourceModel) accountKey() (crypto.Signer, error) {
+	if !d.AccountKeyPEM.IsNull() && !d.AccountKeyPEM.IsUnknown() {
+		block, _ := pem.Decode([]byte(d.AccountKeyPEM.ValueString()))
+		if block == nil {
+			return nil, fmt.Errorf("failed to decode ACME account key")
+		}
+		key, err := parsePrivateKey(block)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported ACME account key type %T", key)
+		}
+		return signer, nil
+	}
+	key, err := generatePrivateKey(keyAlgorithmECDSAP256)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := encodePrivateKeyPEM(key)
+	if err != nil {
+		return nil, err
+	}
+	d.AccountKeyPEM = types.StringValue(keyPEM)
+	return key, nil
+}
+
+// fileName returns the name of the certificate in the secret, which defaults to
+// the first domain. It is empty while the domains are unknown.
+func (d *ACMECertificateResourceModel) fileName(ctx context.Context) (string, diag.Diagnostics) {
+	if !d.FileName.IsNull() {
+		return d.FileName.ValueString(), nil
+	}
+	if d.Domains.IsUnknown() {
+		return "", nil
+	}
+	var domains []string
+	diags := d.Domains.ElementsAs(ctx, &domains, false)
+	if diags.HasError() || len(domains) == 0 {
+		return "", diags
+	}
+	return domains[0] + ".pem", diags
+}
+
+func (d *ACMECertificateResourceModel) renewalDays() int32 {
+	if d.RenewalDays.IsNull() || d.RenewalDays.IsUnknown() {
+		return defaultACMERenewalDays
+	}
+	return d.RenewalDays.ValueInt32()
+}
+
+// setCertificate sets the computed attributes from the stored bundle.
+func (d *ACMECertificateResourceModel) setCertificate(payload []byte, fileName string) error {
+	certs, err := decodeCertificateSecret(payload)
+	if err != nil {
+		return err
+	}
+	data, ok := certs[fileName]
+	if !ok {
+		return fmt.Errorf("certificate %s not found in secret", fileName)
+	}
+	bundle, err := parseCertificateBundle(data)
+	if err != nil {
+		return err
+	}
+	caChain := ""
+	for _, cert := range bundle.chain[1:] {
+		caChain += encodeCertificatePEM(cert.Raw)
+	}
+	d.CertificatePEM = types.StringValue(encodeCertificatePEM(bundle.leaf().Raw))
+	d.CAChainPEM = types.StringValue(caChain)
+	d.NotAfter = types.StringValue(bundle.leaf().NotAfter.UTC().Format(time.RFC3339))
+	return nil
+}
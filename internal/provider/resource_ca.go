@@ -0,0 +1,353 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int32planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &CAResource{}
+var _ resource.ResourceWithValidateConfig = &CAResource{}
+
+const defaultCAValidityDays = 3650
+
+func NewCAResource() resource.Resource {
+	return &CAResource{}
+}
+
+// CAResource defines the resource implementation.
+type CAResource struct {
+	client *secretmanager.Client
+}
+
+// CAResourceModel describes the resource data model.
+type CAResourceModel struct {
+	ProjectId            types.String `tfsdk:"project_id"`
+	Namespace            types.String `tfsdk:"namespace"`
+	Suffix               types.String `tfsdk:"suffix"`
+	CommonName           types.String `tfsdk:"common_name"`
+	Organization         types.String `tfsdk:"organization"`
+	KeyAlgorithm         types.String `tfsdk:"key_algorithm"`
+	ValidityDays         types.Int32  `tfsdk:"validity_days"`
+	Intermediate         types.Bool   `tfsdk:"intermediate"`
+	SecretId             types.String `tfsdk:"secret_id"`
+	CertificatePEM       types.String `tfsdk:"certificate_pem"`
+	IssuerCertificatePEM types.String `tfsdk:"issuer_certificate_pem"`
+	CAChainPEM           types.String `tfsdk:"ca_chain_pem"`
+	NotAfter             types.String `tfsdk:"not_after"`
+}
+
+// caSecret is the JSON payload of a CA secret.
+type caSecret struct {
+	RootCertificate         string `json:"root_certificate"`
+	RootPrivateKey          string `json:"root_private_key"`
+	IntermediateCertificate string `json:"intermediate_certificate,omitempty"`
+	IntermediatePrivateKey  string `json:"intermediate_private_key,omitempty"`
+}
+
+// issuer returns the CA used to issue certificates, which is the intermediate CA
+// when there is one.
+func (c *caSecret) issuer() (*certificateAuthority, error) {
+	if c.IntermediateCertificate != "" {
+		return parseCertificateAuthority(c.IntermediateCertificate, c.IntermediatePrivateKey)
+	}
+	return parseCertificateAuthority(c.RootCertificate, c.RootPrivateKey)
+}
+
+// chain returns the CA certificates in leaf-to-root order.
+func (c *caSecret) chain() string {
+	return c.IntermediateCertificate + c.RootCertificate
+}
+
+func (c *CAResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_ca"
+}
+
+func (c *CAResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Certificate authority for ClearBlade mTLS. The root CA, and optionally an intermediate CA, " +
+			"are generated and stored in GCP Secrets. Use `clearblade-google_issued_certificate` to issue certificates from it",
+
+		Attributes: map[string]schema.Attribute{
+			"project_id": schema.StringAttribute{
+				MarkdownDescription: "GCP project Id for storing the CA",
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"namespace": schema.StringAttribute{
+				MarkdownDescription: "Instance namespace",
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"suffix": schema.StringAttribute{
+				MarkdownDescription: "Secret Id suffix",
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"common_name": schema.StringAttribute{
+				MarkdownDescription: "Common name of the root CA. The intermediate CA uses the same name with an ` Intermediate` suffix",
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"organization": schema.StringAttribute{
+				MarkdownDescription: "Organization of the CA certificates",
+				Optional:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"key_algorithm": schema.StringAttribute{
+				MarkdownDescription: "Private key algorithm, one of `RSA2048`, `RSA4096`, `ECDSA_P256` (default) or `ECDSA_P384`",
+				Optional:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"validity_days": schema.Int32Attribute{
+				MarkdownDescription: "Number of days the CA certificates are valid for. Defaults to 3650",
+				Optional:            true,
+				PlanModifiers:       []planmodifier.Int32{int32planmodifier.RequiresReplace()},
+			},
+			"intermediate": schema.BoolAttribute{
+				MarkdownDescription: "Create an intermediate CA signed by the root and issue certificates from it",
+				Optional:            true,
+				PlanModifiers:       []planmodifier.Bool{boolplanmodifier.RequiresReplace()},
+			},
+			"secret_id": schema.StringAttribute{
+				Computed: true,
+			},
+			"certificate_pem": schema.StringAttribute{
+				MarkdownDescription: "PEM encoded root CA certificate",
+				Computed:            true,
+			},
+			"issuer_certificate_pem": schema.StringAttribute{
+				MarkdownDescription: "PEM encoded certificate of the CA issuing certificates, the intermediate CA if there is one",
+				Computed:            true,
+			},
+			"ca_chain_pem": schema.StringAttribute{
+				MarkdownDescription: "PEM encoded CA certificates in leaf-to-root order",
+				Computed:            true,
+			},
+			"not_after": schema.StringAttribute{
+				MarkdownDescription: "RFC 3339 expiry timestamp of the issuing CA certificate",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (c *CAResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*ClearBladeGoogleProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *ClearBladeGoogleProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	c.client = providerData.client
+}
+
+func (c *CAResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data CAResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	algorithm := keyAlgorithmECDSAP256
+	if !data.KeyAlgorithm.IsNull() {
+		algorithm = keyAlgorithm(data.KeyAlgorithm.ValueString())
+	}
+	validityDays := int32(defaultCAValidityDays)
+	if !data.ValidityDays.IsNull() {
+		validityDays = data.ValidityDays.ValueInt32()
+	}
+	validity := time.Duration(validityDays) * 24 * time.Hour
+
+	root, err := generateCA(data.CommonName.ValueString(), data.Organization.ValueString(), algorithm, validity, nil)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to create root CA", err.Error())
+		return
+	}
+	rootKey, err := encodePrivateKeyPEM(root.key)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to encode root CA private key", err.Error())
+		return
+	}
+	secret := caSecret{
+		RootCertificate: encodeCertificatePEM(root.cert.Raw),
+		RootPrivateKey:  rootKey,
+	}
+	if data.Intermediate.ValueBool() {
+		intermediate, err := generateCA(data.CommonName.ValueString()+" Intermediate", data.Organization.ValueString(), algorithm, validity, root)
+		if err != nil {
+			resp.Diagnostics.AddError("Failed to create intermediate CA", err.Error())
+			return
+		}
+		intermediateKey, err := encodePrivateKeyPEM(intermediate.key)
+		if err != nil {
+			resp.Diagnostics.AddError("Failed to encode intermediate CA private key", err.Error())
+			return
+		}
+		secret.IntermediateCertificate = encodeCertificatePEM(intermediate.cert.Raw)
+		secret.IntermediatePrivateKey = intermediateKey
+	}
+	payload, err := json.Marshal(secret)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to marshal CA", err.Error())
+		return
+	}
+
+	secretId := getSecretId(data.Namespace.ValueString(), data.Suffix.ValueString())
+	if err := createSecret(ctx, c.client, data.ProjectId.ValueString(), secretId); err != nil {
+		resp.Diagnostics.AddError("Failed to create secret", err.Error())
+		return
+	}
+	if err := addSecretVersion(ctx, c.client, data.ProjectId.ValueString(), secretId, payload); err != nil {
+		resp.Diagnostics.AddError("Failed to add CA to secret", err.Error())
+		return
+	}
+	data.SecretId = types.StringValue(secretId)
+	if err := data.setCertificates(&secret); err != nil {
+		resp.Diagnostics.AddError("Failed to parse CA", err.Error())
+		return
+	}
+	tflog.Trace(ctx, "created and stored CA to GCP secrets")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (c *CAResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data CAResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	secretId := getSecretId(data.Namespace.ValueString(), data.Suffix.ValueString())
+	secret, err := readCASecret(ctx, c.client, data.ProjectId.ValueString(), secretId)
+	if err != nil {
+		if isSecretNotFound(err) {
+			tflog.Warn(ctx, "CA secret not found, removing from state")
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.AddError("Failed to read CA", err.Error())
+		return
+	}
+	data.SecretId = types.StringValue(secretId)
+	if err := data.setCertificates(secret); err != nil {
+		resp.Diagnostics.AddError("Failed to parse CA", err.Error())
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (c *CAResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data, state CAResourceModel
+
+	// All configurable attributes require replacement, so only carry over the
+	// computed values.
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.SecretId = state.SecretId
+	data.CertificatePEM = state.CertificatePEM
+	data.IssuerCertificatePEM = state.IssuerCertificatePEM
+	data.CAChainPEM = state.CAChainPEM
+	data.NotAfter = state.NotAfter
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (c *CAResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data CAResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	secretId := getSecretId(data.Namespace.ValueString(), data.Suffix.ValueString())
+	resource := getSecretResourceName(data.ProjectId.ValueString(), secretId)
+	delReq := &secretmanagerpb.DeleteSecretRequest{
+		Name: resource,
+	}
+	if err := c.client.DeleteSecret(ctx, delReq); err != nil {
+		resp.Diagnostics.AddError("Failed to delete CA", err.Error())
+		return
+	}
+}
+
+func (c *CAResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data CAResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := validateKeyAlgorithm(data.KeyAlgorithm); err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("key_algorithm"), "Invalid key_algorithm attribute", err.Error())
+	}
+	if !data.ValidityDays.IsNull() && !data.ValidityDays.IsUnknown() && data.ValidityDays.ValueInt32() < 1 {
+		resp.Diagnostics.AddAttributeError(path.Root("validity_days"), "Invalid validity_days attribute", "validity_days must be at least 1")
+	}
+}
+
+func (d *CAResourceModel) setCertificates(secret *caSecret) error {
+	issuer, err := secret.issuer()
+	if err != nil {
+		return err
+	}
+	d.CertificatePEM = types.StringValue(secret.RootCertificate)
+	d.IssuerCertificatePEM = types.StringValue(encodeCertificatePEM(issuer.cert.Raw))
+	d.CAChainPEM = types.StringValue(secret.chain())
+	d.NotAfter = types.StringValue(issuer.cert.NotAfter.UTC().Format(time.RFC3339))
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func readCASecret(ctx context.Context, client *secretmanager.Client, projectId, secretId string) (*caSecret, error) {
+	payload, err := getLatestSecretVersion(ctx, client, projectId, secretId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CA secret: %w", err)
+	}
+	secret := &caSecret{}
+	if err := json.Unmarshal(payload, secret); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal CA secret: %w", err)
+	}
+	return secret, nil
+}
@@ -0,0 +1,375 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &ClientCABundleResource{}
+var _ resource.ResourceWithValidateConfig = &ClientCABundleResource{}
+
+func NewClientCABundleResource() resource.Resource {
+	return &ClientCABundleResource{}
+}
+
+// ClientCABundleResource defines the resource implementation.
+type ClientCABundleResource struct {
+	client *secretmanager.Client
+}
+
+// ClientCABundleResourceModel describes the resource data model.
+type ClientCABundleResourceModel struct {
+	ProjectId      types.String `tfsdk:"project_id"`
+	Namespace      types.String `tfsdk:"namespace"`
+	Suffix         types.String `tfsdk:"suffix"`
+	CACertificates types.List   `tfsdk:"ca_certificates"`
+	CRLs           types.List   `tfsdk:"crls"`
+	SecretId       types.String `tfsdk:"secret_id"`
+	BundlePEM      types.String `tfsdk:"bundle_pem"`
+	CRLSecretId    types.String `tfsdk:"crl_secret_id"`
+	CRLPEM         types.String `tfsdk:"crl_pem"`
+	Fingerprints   types.List   `tfsdk:"fingerprints"`
+}
+
+func (c *ClientCABundleResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_client_ca_bundle"
+}
+
+func (c *ClientCABundleResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Trusted client CA bundle for HAProxy mTLS verification. The CA certificates are verified, " +
+			"deduplicated and stored in GCP Secrets as PEM for the HAProxy `ca-file`. The optional CRLs are stored in a separate " +
+			"`<namespace><suffix>-crl` secret for the HAProxy `crl-file`, as HAProxy ignores CRLs in the `ca-file`",
+
+		Attributes: map[string]schema.Attribute{
+			"project_id": schema.StringAttribute{
+				MarkdownDescription: "GCP project Id for storing the bundle",
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"namespace": schema.StringAttribute{
+				MarkdownDescription: "Instance namespace",
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"suffix": schema.StringAttribute{
+				MarkdownDescription: "Secret Id suffix",
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"ca_certificates": schema.ListAttribute{
+				MarkdownDescription: "PEM encoded CA certificates, for example `clearblade-google_ca.ca_chain_pem`. " +
+					"Each entry may hold several certificates",
+				ElementType: types.StringType,
+				Required:    true,
+			},
+			"crls": schema.ListAttribute{
+				MarkdownDescription: "PEM encoded certificate revocation lists, each signed by one of the CAs and not past its next update",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"secret_id": schema.StringAttribute{
+				Computed: true,
+			},
+			"bundle_pem": schema.StringAttribute{
+				MarkdownDescription: "Stored PEM encoded CA certificates",
+				Computed:            true,
+			},
+			"crl_secret_id": schema.StringAttribute{
+				MarkdownDescription: "Secret Id of the CRLs, null without `crls`",
+				Computed:            true,
+			},
+			"crl_pem": schema.StringAttribute{
+				MarkdownDescription: "Stored PEM encoded CRLs, null without `crls`",
+				Computed:            true,
+			},
+			"fingerprints": schema.ListAttribute{
+				MarkdownDescription: "SHA-256 fingerprints of the CA certificates in the bundle",
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (c *ClientCABundleResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*ClearBladeGoogleProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *ClearBladeGoogleProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	c.client = providerData.client
+}
+
+func (c *ClientCABundleResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data ClientCABundleResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	secretId := getSecretId(data.Namespace.ValueString(), data.Suffix.ValueString())
+	if err := createSecret(ctx, c.client, data.ProjectId.ValueString(), secretId); err != nil {
+		resp.Diagnostics.AddError("Failed to create secret", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(c.storeBundle(ctx, &data, secretId)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	tflog.Trace(ctx, "created and stored client CA bundle to GCP secrets")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (c *ClientCABundleResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data ClientCABundleResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	secretId := getSecretId(data.Namespace.ValueString(), data.Suffix.ValueString())
+	payload, err := getLatestSecretVersion(ctx, c.client, data.ProjectId.ValueString(), secretId)
+	if err != nil {
+		if isSecretNotFound(err) {
+			tflog.Warn(ctx, "Client CA bundle secret not found, removing from state")
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.AddError("Failed to get client CA bundle secret", err.Error())
+		return
+	}
+	data.SecretId = types.StringValue(secretId)
+	data.BundlePEM = types.StringValue(string(payload))
+	if !data.CRLSecretId.IsNull() {
+		crlPEM, err := getLatestSecretVersion(ctx, c.client, data.ProjectId.ValueString(), data.CRLSecretId.ValueString())
+		if err != nil {
+			if isSecretNotFound(err) {
+				tflog.Warn(ctx, "Client CRL secret not found, removing from state")
+				resp.State.RemoveResource(ctx)
+				return
+			}
+			resp.Diagnostics.AddError("Failed to get client CRL secret", err.Error())
+			return
+		}
+		data.CRLPEM = types.StringValue(string(crlPEM))
+	}
+	resp.Diagnostics.Append(data.setFingerprints(ctx, storedCertificateFingerprints(payload))...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (c *ClientCABundleResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data ClientCABundleResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	secretId := getSecretId(data.Namespace.ValueString(), data.Suffix.ValueString())
+	if err := createSecret(ctx, c.client, data.ProjectId.ValueString(), secretId); err != nil {
+		resp.Diagnostics.AddError("Failed to create secret", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(c.storeBundle(ctx, &data, secretId)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (c *ClientCABundleResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data ClientCABundleResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	secretId := getSecretId(data.Namespace.ValueString(), data.Suffix.ValueString())
+	resource := getSecretResourceName(data.ProjectId.ValueString(), secretId)
+	delReq := &secretmanagerpb.DeleteSecretRequest{
+		Name: resource,
+	}
+	if err := c.client.DeleteSecret(ctx, delReq); err != nil {
+		resp.Diagnostics.AddError("Failed to delete client CA bundle", err.Error())
+		return
+	}
+	if !data.CRLSecretId.IsNull() {
+		if err := deleteSecret(ctx, c.client, data.ProjectId.ValueString(), data.CRLSecretId.ValueString()); err != nil {
+			resp.Diagnostics.AddError("Failed to delete client CRLs", err.Error())
+			return
+		}
+	}
+}
+
+func (c *ClientCABundleResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data ClientCABundleResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// CA certificates often come from other resources, so the bundle can only be
+	// checked once every value is known.
+	caCerts, caKnown := knownStrings(ctx, data.CACertificates, &resp.Diagnostics)
+	crls, crlsKnown := knownStrings(ctx, data.CRLs, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() || !caKnown || !crlsKnown {
+		return
+	}
+	if len(caCerts) == 0 {
+		resp.Diagnostics.AddAttributeError(path.Root("ca_certificates"), "Invalid ca_certificates attribute",
+			"at least one CA certificate is required")
+		return
+	}
+	certs, warnings, err := parseClientCACertificates(caCerts, time.Now())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("ca_certificates"), "Invalid ca_certificates attribute", err.Error())
+		return
+	}
+	for _, warning := range warnings {
+		resp.Diagnostics.AddAttributeWarning(path.Root("ca_certificates"), "Expired CA certificate", warning)
+	}
+	if _, err := parseClientCRLs(crls, certs, time.Now()); err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("crls"), "Invalid crls attribute", err.Error())
+	}
+}
+
+func (c *ClientCABundleResource) storeBundle(ctx context.Context, data *ClientCABundleResourceModel, secretId string) diag.Diagnostics {
+	var diags diag.Diagnostics
+	var caCerts, crls []string
+	diags.Append(data.CACertificates.ElementsAs(ctx, &caCerts, false)...)
+	diags.Append(data.CRLs.ElementsAs(ctx, &crls, false)...)
+	if diags.HasError() {
+		return diags
+	}
+	bundle, warnings, err := buildClientCABundle(caCerts, crls, time.Now())
+	if err != nil {
+		diags.AddError("Invalid client CA bundle", err.Error())
+		return diags
+	}
+	for _, warning := range warnings {
+		diags.AddAttributeWarning(path.Root("ca_certificates"), "Expired CA certificate", warning)
+	}
+	bundlePEM := bundle.pem()
+	if err := addSecretVersion(ctx, c.client, data.ProjectId.ValueString(), secretId, []byte(bundlePEM)); err != nil {
+		diags.AddError("Failed to add client CA bundle to secret", err.Error())
+		return diags
+	}
+	data.SecretId = types.StringValue(secretId)
+	data.BundlePEM = types.StringValue(bundlePEM)
+
+	crlSecretId := secretId + "-crl"
+	data.CRLSecretId = types.StringNull()
+	data.CRLPEM = types.StringNull()
+	if len(bundle.crls) > 0 {
+		crlPEM := bundle.crlPEM()
+		if err := createSecret(ctx, c.client, data.ProjectId.ValueString(), crlSecretId); err != nil {
+			diags.AddError("Failed to create CRL secret", err.Error())
+			return diags
+		}
+		if err := addSecretVersion(ctx, c.client, data.ProjectId.ValueString(), crlSecretId, []byte(crlPEM)); err != nil {
+			diags.AddError("Failed to add client CRLs to secret", err.Error())
+			return diags
+		}
+		data.CRLSecretId = types.StringValue(crlSecretId)
+		data.CRLPEM = types.StringValue(crlPEM)
+	} else if secretExists(ctx, c.client, data.ProjectId.ValueString(), crlSecretId) {
+		// All CRLs were removed
+		if err := deleteSecret(ctx, c.client, data.ProjectId.ValueString(), crlSecretId); err != nil {
+			diags.AddError("Failed to delete client CRLs", err.Error())
+			return diags
+		}
+	}
+	diags.Append(data.setFingerprints(ctx, bundle.fingerprints())...)
+	return diags
+}
+
+func (d *ClientCABundleResourceModel) setFingerprints(ctx context.Context, fingerprints []string) diag.Diagnostics {
+	list, diags := types.ListValueFrom(ctx, types.StringType, fingerprints)
+	d.Fingerprints = list
+	return diags
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// knownStrings returns the elements of a string list and whether the list and
+// all of its elements are known.
+func knownStrings(ctx context.Context, list types.List, diags *diag.Diagnostics) ([]string, bool) {
+	if list.IsUnknown() {
+		return nil, false
+	}
+	var elements []types.String
+	diags.Append(list.ElementsAs(ctx, &elements, false)...)
+	values := make([]string, 0, len(elements))
+	for _, element := range elements {
+		if element.IsUnknown() {
+			return nil, false
+		}
+		values = append(values, element.ValueString())
+	}
+	return values, true
+}
+
+// storedCertificateFingerprints returns the hex SHA-256 fingerprints of the
+// certificates in stored PEM data.
+func storedCertificateFingerprints(data []byte) []string {
+	fingerprints := []string{}
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return fingerprints
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		fingerprint := sha256.Sum256(block.Bytes)
+		fingerprints = append(fingerprints, hex.EncodeToString(fingerprint[:]))
+	}
+}
@@ -0,0 +1,485 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int32validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &DatabaseCredentialsResource{}
+var _ resource.ResourceWithModifyPlan = &DatabaseCredentialsResource{}
+
+type databaseEngine string
+
+const (
+	databaseEnginePostgres = databaseEngine("postgres")
+	databaseEngineRedis    = databaseEngine("redis")
+)
+
+const (
+	defaultPostgresPort           = 5432
+	defaultRedisPort              = 6379
+	defaultPostgresUsername       = "clearblade"
+	defaultPostgresDatabase       = "clearblade"
+	defaultPostgresSSLMode        = "require"
+	defaultRedisSSLMode           = "disable"
+	defaultDatabasePasswordLength = 32
+)
+
+func NewDatabaseCredentialsResource() resource.Resource {
+	return &DatabaseCredentialsResource{}
+}
+
+// DatabaseCredentialsResource defines the resource implementation.
+type DatabaseCredentialsResource struct {
+	client *secretmanager.Client
+}
+
+// DatabaseCredentialsResourceModel describes the resource data model.
+type DatabaseCredentialsResourceModel struct {
+	ProjectId        types.String `tfsdk:"project_id"`
+	Namespace        types.String `tfsdk:"namespace"`
+	Suffix           types.String `tfsdk:"suffix"`
+	Engine           types.String `tfsdk:"engine"`
+	Host             types.String `tfsdk:"host"`
+	Port             types.Int32  `tfsdk:"port"`
+	Username         types.String `tfsdk:"username"`
+	Database         types.String `tfsdk:"database"`
+	SSLMode          types.String `tfsdk:"sslmode"`
+	PasswordLength   types.Int32  `tfsdk:"password_length"`
+	Special          types.Bool   `tfsdk:"special"`
+	Keepers          types.Map    `tfsdk:"keepers"`
+	Labels           types.Map    `tfsdk:"labels"`
+	SecretId         types.String `tfsdk:"secret_id"`
+	UsernameSecretId types.String `tfsdk:"username_secret_id"`
+	PasswordSecretId types.String `tfsdk:"password_secret_id"`
+	URISecretId      types.String `tfsdk:"uri_secret_id"`
+	Password         types.String `tfsdk:"password"`
+	URI              types.String `tfsdk:"uri"`
+}
+
+// databaseCredentials is the JSON payload of the credentials secret.
+type databaseCredentials struct {
+	User     string `json:"user,omitempty"`
+	Password string `json:"password"`
+	Host     string `json:"host"`
+	Port     int32  `json:"port"`
+	Database string `json:"database,omitempty"`
+	SSLMode  string `json:"sslmode"`
+	URI      string `json:"uri"`
+}
+
+func (d *DatabaseCredentialsResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_database_credentials"
+}
+
+func (d *DatabaseCredentialsResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Credentials of an external Postgres (Cloud SQL) or Redis (Memorystore) instance. " +
+			"A random password is generated and stored with the connection details as JSON in the `<namespace><suffix>` secret, " +
+			"and the username, password and connection URI in the `-username`, `-password` and `-uri` secrets. " +
+			"A new password is only generated when `password_length`, `special` or `keepers` change",
+
+		Attributes: map[string]schema.Attribute{
+			"project_id": schema.StringAttribute{
+				MarkdownDescription: "GCP project Id for storing the credentials",
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"namespace": schema.StringAttribute{
+				MarkdownDescription: "Instance namespace",
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"suffix": schema.StringAttribute{
+				MarkdownDescription: "Secret Id suffix",
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"engine": schema.StringAttribute{
+				MarkdownDescription: "Database engine, `postgres` or `redis`",
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+				Validators: []validator.String{
+					stringvalidator.OneOf(string(databaseEnginePostgres), string(databaseEngineRedis)),
+				},
+			},
+			"host": schema.StringAttribute{
+				MarkdownDescription: "Host name or IP address of the instance",
+				Required:            true,
+			},
+			"port": schema.Int32Attribute{
+				MarkdownDescription: "Port of the instance. Defaults to 5432 for Postgres and 6379 for Redis",
+				Optional:            true,
+				Validators:          []validator.Int32{int32validator.Between(1, 65535)},
+			},
+			"username": schema.StringAttribute{
+				MarkdownDescription: "Database user. Defaults to `clearblade` for Postgres, Redis uses no user unless set",
+				Optional:            true,
+			},
+			"database": schema.StringAttribute{
+				MarkdownDescription: "Postgres database name. Defaults to `clearblade`",
+				Optional:            true,
+			},
+			"sslmode": schema.StringAttribute{
+				MarkdownDescription: "Postgres `sslmode`, defaults to `require`. For Redis, any value but `disable` uses a `rediss://` URI, " +
+					"defaults to `disable`",
+				Optional: true,
+				Validators: []validator.String{
+					stringvalidator.OneOf("disable", "allow", "prefer", "require", "verify-ca", "verify-full"),
+				},
+			},
+			"password_length": schema.Int32Attribute{
+				MarkdownDescription: "Length of the generated password. Defaults to 32",
+				Optional:            true,
+				Validators:          []validator.Int32{int32validator.Between(16, 128)},
+			},
+			"special": schema.BoolAttribute{
+				MarkdownDescription: "Include special characters in the password. Defaults to false",
+				Optional:            true,
+			},
+			"keepers": schema.MapAttribute{
+				ElementType:         types.StringType,
+				MarkdownDescription: "Arbitrary values which generate a new password when changed",
+				Optional:            true,
+			},
+			"labels": schema.MapAttribute{
+				ElementType:         types.StringType,
+				MarkdownDescription: "Labels of the secrets",
+				Optional:            true,
+			},
+			"secret_id": schema.StringAttribute{
+				MarkdownDescription: "Secret Id of the JSON credentials",
+				Computed:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"username_secret_id": schema.StringAttribute{
+				MarkdownDescription: "Secret Id of the username, null when there is no user",
+				Computed:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"password_secret_id": schema.StringAttribute{
+				MarkdownDescription: "Secret Id of the password",
+				Computed:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"uri_secret_id": schema.StringAttribute{
+				MarkdownDescription: "Secret Id of the connection URI",
+				Computed:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"password": schema.StringAttribute{
+				MarkdownDescription: "Generated password, for example to create the database user",
+				Computed:            true,
+				Sensitive:           true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"uri": schema.StringAttribute{
+				MarkdownDescription: "Connection URI",
+				Computed:            true,
+				Sensitive:           true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+		},
+	}
+}
+
+func (d *DatabaseCredentialsResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*ClearBladeGoogleProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *ClearBladeGoogleProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.client = providerData.client
+}
+
+func (d *DatabaseCredentialsResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data DatabaseCredentialsResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := d.generatePassword(&data); err != nil {
+		resp.Diagnostics.AddError("Failed to generate password", err.Error())
+		return
+	}
+	if err := d.store(ctx, &data, false); err != nil {
+		resp.Diagnostics.AddError("Failed to store database credentials", err.Error())
+		return
+	}
+	tflog.Trace(ctx, "created and stored database credentials to GCP secrets")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (d *DatabaseCredentialsResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data DatabaseCredentialsResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	payload, err := getLatestSecretVersion(ctx, d.client, data.ProjectId.ValueString(), data.SecretId.ValueString())
+	if err != nil {
+		if isSecretNotFound(err) {
+			tflog.Warn(ctx, "Database credentials secret not found, removing from state")
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.AddError("Failed to get database credentials secret", err.Error())
+		return
+	}
+	var credentials databaseCredentials
+	if err := json.Unmarshal(payload, &credentials); err != nil {
+		resp.Diagnostics.AddError("Failed to parse database credentials secret", err.Error())
+		return
+	}
+	// Reflect a password changed outside of Terraform, the other attributes
+	// are configuration and rewritten on the next apply when they differ.
+	data.Password = types.StringValue(credentials.Password)
+	data.URI = types.StringValue(credentials.URI)
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (d *DatabaseCredentialsResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data, state DatabaseCredentialsResourceModel
+
+	// Read Terraform plan and prior state data into the models
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// ModifyPlan leaves the password unknown when a new one is needed, other
+	// changes keep the current password.
+	if data.Password.IsUnknown() {
+		if err := d.generatePassword(&data); err != nil {
+			resp.Diagnostics.AddError("Failed to generate password", err.Error())
+			return
+		}
+	}
+	// Labels removed from the configuration are removed from the secrets
+	if err := d.store(ctx, &data, !state.Labels.IsNull()); err != nil {
+		resp.Diagnostics.AddError("Failed to store database credentials", err.Error())
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (d *DatabaseCredentialsResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data DatabaseCredentialsResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	for _, secretId := range []types.String{data.UsernameSecretId, data.PasswordSecretId, data.URISecretId, data.SecretId} {
+		if secretId.IsNull() {
+			continue
+		}
+		if err := deleteSecret(ctx, d.client, data.ProjectId.ValueString(), secretId.ValueString()); err != nil {
+			resp.Diagnostics.AddError("Failed to delete database credentials", err.Error())
+			return
+		}
+	}
+}
+
+func (d *DatabaseCredentialsResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to regenerate when the resource is created or destroyed.
+	if req.Plan.Raw.IsNull() || req.State.Raw.IsNull() {
+		return
+	}
+
+	var plan, state DatabaseCredentialsResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	newPassword := plan.passwordLength() != state.passwordLength() || !plan.Special.Equal(state.Special) || !plan.Keepers.Equal(state.Keepers)
+	if newPassword {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("password"), types.StringUnknown())...)
+	}
+	// The URI is built from host, port, username, database, sslmode and the
+	// password, the plan keeps the current one unless any of them change.
+	planned, current := plan.credentials(), state.credentials()
+	if newPassword || planned.URI != current.URI {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("uri"), types.StringUnknown())...)
+	}
+	// The username secret is only created or deleted when a Redis user is set or removed.
+	if (planned.User == "") != (current.User == "") {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("username_secret_id"), types.StringUnknown())...)
+	}
+}
+
+func (d *DatabaseCredentialsResource) generatePassword(data *DatabaseCredentialsResourceModel) error {
+	// At least one character of each class, as required by the Cloud SQL password policy
+	opts := randomStringOptions{minUpper: 1, minLower: 1, minNumeric: 1}
+	if data.Special.ValueBool() {
+		opts.special = true
+		opts.minSpecial = 1
+	}
+	password, err := generateRandomString(int(data.passwordLength()), opts)
+	if err != nil {
+		return err
+	}
+	data.Password = types.StringValue(password)
+	return nil
+}
+
+// store writes the JSON credentials and the username, password and URI secrets.
+// Existing secrets keep their labels when labels are not configured, unless
+// clearLabels is set.
+func (d *DatabaseCredentialsResource) store(ctx context.Context, data *DatabaseCredentialsResourceModel, clearLabels bool) error {
+	labels, err := secretLabels(ctx, data.Labels)
+	if err != nil {
+		return err
+	}
+	if labels == nil && clearLabels {
+		labels = map[string]string{}
+	}
+	credentials := data.credentials()
+	payload, err := json.Marshal(credentials)
+	if err != nil {
+		return err
+	}
+
+	projectId := data.ProjectId.ValueString()
+	secretId := getSecretId(data.Namespace.ValueString(), data.Suffix.ValueString())
+	secrets := []struct {
+		id      *types.String
+		payload []byte
+	}{
+		{&data.SecretId, payload},
+		{&data.PasswordSecretId, []byte(credentials.Password)},
+		{&data.URISecretId, []byte(credentials.URI)},
+		{&data.UsernameSecretId, []byte(credentials.User)},
+	}
+	names := []string{secretId, secretId + "-password", secretId + "-uri", secretId + "-username"}
+	for i, secret := range secrets {
+		if len(secret.payload) == 0 {
+			// Redis without a user, remove the secret of a previous user
+			if secretExists(ctx, d.client, projectId, names[i]) {
+				if err := deleteSecret(ctx, d.client, projectId, names[i]); err != nil {
+					return err
+				}
+			}
+			*secret.id = types.StringNull()
+			continue
+		}
+		if err := createSecretWithLabels(ctx, d.client, projectId, names[i], labels); err != nil {
+			return fmt.Errorf("failed to create secret %s: %w", names[i], err)
+		}
+		if err := addSecretVersionIfChanged(ctx, d.client, projectId, names[i], secret.payload); err != nil {
+			return fmt.Errorf("failed to add version to secret %s: %w", names[i], err)
+		}
+		*secret.id = types.StringValue(names[i])
+	}
+	data.URI = types.StringValue(credentials.URI)
+	return nil
+}
+
+func (d *DatabaseCredentialsResourceModel) credentials() databaseCredentials {
+	engine := databaseEngine(d.Engine.ValueString())
+	c := databaseCredentials{
+		User:     d.Username.ValueString(),
+		Password: d.Password.ValueString(),
+		Host:     d.Host.ValueString(),
+		Port:     d.Port.ValueInt32(),
+		Database: d.Database.ValueString(),
+		SSLMode:  d.SSLMode.ValueString(),
+	}
+	hostPort := func() string {
+		return net.JoinHostPort(c.Host, strconv.Itoa(int(c.Port)))
+	}
+
+	switch engine {
+	case databaseEnginePostgres:
+		if d.Username.IsNull() {
+			c.User = defaultPostgresUsername
+		}
+		if d.Port.IsNull() {
+			c.Port = defaultPostgresPort
+		}
+		if d.Database.IsNull() {
+			c.Database = defaultPostgresDatabase
+		}
+		if d.SSLMode.IsNull() {
+			c.SSLMode = defaultPostgresSSLMode
+		}
+		uri := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(c.User, c.Password),
+			Host:     hostPort(),
+			Path:     "/" + c.Database,
+			RawQuery: url.Values{"sslmode": {c.SSLMode}}.Encode(),
+		}
+		c.URI = uri.String()
+	case databaseEngineRedis:
+		if d.Port.IsNull() {
+			c.Port = defaultRedisPort
+		}
+		if d.SSLMode.IsNull() {
+			c.SSLMode = defaultRedisSSLMode
+		}
+		uri := url.URL{
+			Scheme: "redis",
+			User:   url.UserPassword(c.User, c.Password),
+			Host:   hostPort(),
+		}
+		if c.SSLMode != "disable" {
+			uri.Scheme = "rediss"
+		}
+		c.URI = uri.String()
+	}
+	return c
+}
+
+func (d *DatabaseCredentialsResourceModel) passwordLength() int32 {
+	if d.PasswordLength.IsNull() {
+		return defaultDatabasePasswordLength
+	}
+	return d.PasswordLength.ValueInt32()
+}
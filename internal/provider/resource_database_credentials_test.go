@@ -0,0 +1,169 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestDatabaseCredentialsModifyPlan(t *testing.T) {
+	keepers, diags := types.MapValueFrom(context.Background(), types.StringType, map[string]string{"rotate": "1"})
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	r := &DatabaseCredentialsResource{}
+	for _, tc := range []struct {
+		name     string
+		engine   databaseEngine
+		plan     func(*DatabaseCredentialsResourceModel)
+		password bool
+		uri      bool
+		username bool
+	}{
+		{name: "unchanged", engine: databaseEnginePostgres},
+		{
+			name:   "labels changed",
+			engine: databaseEnginePostgres,
+			plan: func(d *DatabaseCredentialsResourceModel) {
+				d.Labels = types.MapValueMust(types.StringType, map[string]attr.Value{"team": types.StringValue("iot")})
+			},
+		},
+		{
+			// The default port of Postgres
+			name:   "port set to the default",
+			engine: databaseEnginePostgres,
+			plan:   func(d *DatabaseCredentialsResourceModel) { d.Port = types.Int32Value(defaultPostgresPort) },
+		},
+		{
+			name:   "host changed",
+			engine: databaseEnginePostgres,
+			plan:   func(d *DatabaseCredentialsResourceModel) { d.Host = types.StringValue("10.0.0.2") },
+			uri:    true,
+		},
+		{
+			name:   "port changed",
+			engine: databaseEnginePostgres,
+			plan:   func(d *DatabaseCredentialsResourceModel) { d.Port = types.Int32Value(6432) },
+			uri:    true,
+		},
+		{
+			name:   "username changed",
+			engine: databaseEnginePostgres,
+			plan:   func(d *DatabaseCredentialsResourceModel) { d.Username = types.StringValue("other") },
+			uri:    true,
+		},
+		{
+			name:   "database changed",
+			engine: databaseEnginePostgres,
+			plan:   func(d *DatabaseCredentialsResourceModel) { d.Database = types.StringValue("other") },
+			uri:    true,
+		},
+		{
+			name:   "sslmode changed",
+			engine: databaseEnginePostgres,
+			plan:   func(d *DatabaseCredentialsResourceModel) { d.SSLMode = types.StringValue("verify-full") },
+			uri:    true,
+		},
+		{
+			name:     "keepers changed",
+			engine:   databaseEnginePostgres,
+			plan:     func(d *DatabaseCredentialsResourceModel) { d.Keepers = keepers },
+			password: true,
+			uri:      true,
+		},
+		{
+			name:     "password_length changed",
+			engine:   databaseEnginePostgres,
+			plan:     func(d *DatabaseCredentialsResourceModel) { d.PasswordLength = types.Int32Value(64) },
+			password: true,
+			uri:      true,
+		},
+		{
+			name:     "redis user set",
+			engine:   databaseEngineRedis,
+			plan:     func(d *DatabaseCredentialsResourceModel) { d.Username = types.StringValue("default") },
+			uri:      true,
+			username: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			state := testDatabaseCredentialsState(tc.engine)
+			plan := state
+			if tc.plan != nil {
+				tc.plan(&plan)
+			}
+			resp := testDatabaseCredentialsModifyPlan(t, r, state, plan)
+			if resp.Diagnostics.HasError() {
+				t.Fatalf("ModifyPlan: %v", resp.Diagnostics)
+			}
+			for attribute, unknown := range map[string]bool{"password": tc.password, "uri": tc.uri, "username_secret_id": tc.username} {
+				var value types.String
+				resp.Diagnostics.Append(resp.Plan.GetAttribute(context.Background(), path.Root(attribute), &value)...)
+				if resp.Diagnostics.HasError() {
+					t.Fatalf("GetAttribute(%s): %v", attribute, resp.Diagnostics)
+				}
+				if value.IsUnknown() != unknown {
+					t.Errorf("%s unknown = %t, want %t", attribute, value.IsUnknown(), unknown)
+				}
+			}
+		})
+	}
+}
+
+func testDatabaseCredentialsState(engine databaseEngine) DatabaseCredentialsResourceModel {
+	d := DatabaseCredentialsResourceModel{
+		ProjectId:        types.StringValue("project"),
+		Namespace:        types.StringValue("namespace"),
+		Suffix:           types.StringValue("-db"),
+		Engine:           types.StringValue(string(engine)),
+		Host:             types.StringValue("10.0.0.1"),
+		Port:             types.Int32Null(),
+		Username:         types.StringNull(),
+		Database:         types.StringNull(),
+		SSLMode:          types.StringNull(),
+		PasswordLength:   types.Int32Null(),
+		Special:          types.BoolNull(),
+		Keepers:          types.MapNull(types.StringType),
+		Labels:           types.MapNull(types.StringType),
+		SecretId:         types.StringValue("namespace-db"),
+		UsernameSecretId: types.StringNull(),
+		PasswordSecretId: types.StringValue("namespace-db-password"),
+		URISecretId:      types.StringValue("namespace-db-uri"),
+		Password:         types.StringValue("password"),
+	}
+	credentials := d.credentials()
+	if credentials.User != "" {
+		d.UsernameSecretId = types.StringValue("namespace-db-username")
+	}
+	d.URI = types.StringValue(credentials.URI)
+	return d
+}
+
+func testDatabaseCredentialsModifyPlan(t *testing.T, r *DatabaseCredentialsResource, state, plan DatabaseCredentialsResourceModel) *resource.ModifyPlanResponse {
+	t.Helper()
+	ctx := context.Background()
+	schemaResp := &resource.SchemaResponse{}
+	r.Schema(ctx, resource.SchemaRequest{}, schemaResp)
+	s := schemaResp.Schema
+
+	req := resource.ModifyPlanRequest{
+		State: tfsdk.State{Schema: s, Raw: tftypes.NewValue(s.Type().TerraformType(ctx), nil)},
+		Plan:  tfsdk.Plan{Schema: s, Raw: tftypes.NewValue(s.Type().TerraformType(ctx), nil)},
+	}
+	if diags := req.State.Set(ctx, &state); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if diags := req.Plan.Set(ctx, &plan); diags.HasError() {
+		t.Fatal(diags)
+	}
+	resp := &resource.ModifyPlanResponse{Plan: req.Plan}
+	r.ModifyPlan(ctx, req, resp)
+	return resp
+}
@@ -0,0 +1,325 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	"github.com/hashicorp/terraform-plugin-framework-validators/resourcevalidator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &ImagePullerSecretResource{}
+var _ resource.ResourceWithConfigValidators = &ImagePullerSecretResource{}
+var _ resource.ResourceWithModifyPlan = &ImagePullerSecretResource{}
+
+// jsonKeyUsername is the registry username for authenticating with a service
+// account key.
+const jsonKeyUsername = "_json_key"
+
+func NewImagePullerSecretResource() resource.Resource {
+	return &ImagePullerSecretResource{}
+}
+
+// ImagePullerSecretResource defines the resource implementation.
+type ImagePullerSecretResource struct {
+	client *secretmanager.Client
+}
+
+// ImagePullerSecretResourceModel describes the resource data model.
+type ImagePullerSecretResourceModel struct {
+	ProjectId                 types.String `tfsdk:"project_id"`
+	Namespace                 types.String `tfsdk:"namespace"`
+	Suffix                    types.String `tfsdk:"suffix"`
+	Registry                  types.String `tfsdk:"registry"`
+	ServiceAccountKey         types.String `tfsdk:"service_account_key"`
+	ServiceAccountKeySecretId types.String `tfsdk:"service_account_key_secret_id"`
+	SecretId                  types.String `tfsdk:"secret_id"`
+	DockerConfigJSON          types.String `tfsdk:"docker_config_json"`
+	HelmValue                 types.String `tfsdk:"helm_value"`
+}
+
+// dockerConfig is the format of a kubernetes.io/dockerconfigjson secret.
+type dockerConfig struct {
+	Auths map[string]dockerConfigAuth `json:"auths"`
+}
+
+type dockerConfigAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Auth     string `json:"auth"`
+}
+
+func (i *ImagePullerSecretResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_image_puller_secret"
+}
+
+func (i *ImagePullerSecretResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Image puller secret for a container registry. The `.dockerconfigjson` payload is built from a " +
+			"service account key and stored in GCP Secrets. Use `helm_value` for `tf_global.image_puller_secret`",
+
+		Attributes: map[string]schema.Attribute{
+			"project_id": schema.StringAttribute{
+				MarkdownDescription: "GCP project Id for storing the secret",
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"namespace": schema.StringAttribute{
+				MarkdownDescription: "Instance namespace",
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"suffix": schema.StringAttribute{
+				MarkdownDescription: "Secret Id suffix",
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"registry": schema.StringAttribute{
+				MarkdownDescription: "Registry host, for example `gcr.io` or `us-docker.pkg.dev`",
+				Required:            true,
+			},
+			"service_account_key": schema.StringAttribute{
+				MarkdownDescription: "Service account JSON key, plain or base64 encoded like `google_service_account_key.private_key`",
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"service_account_key_secret_id": schema.StringAttribute{
+				MarkdownDescription: "Id of a secret in `project_id` holding the service account JSON key, instead of `service_account_key`. " +
+					"The latest version is used, and a rotated key is picked up during plan",
+				Optional: true,
+			},
+			"secret_id": schema.StringAttribute{
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"docker_config_json": schema.StringAttribute{
+				MarkdownDescription: "Stored `.dockerconfigjson` payload",
+				Computed:            true,
+				Sensitive:           true,
+			},
+			"helm_value": schema.StringAttribute{
+				MarkdownDescription: "Base64 encoded `.dockerconfigjson`, as expected by the helm chart",
+				Computed:            true,
+				Sensitive:           true,
+			},
+		},
+	}
+}
+
+func (i *ImagePullerSecretResource) ConfigValidators(ctx context.Context) []resource.ConfigValidator {
+	return []resource.ConfigValidator{
+		resourcevalidator.ExactlyOneOf(path.MatchRoot("service_account_key"), path.MatchRoot("service_account_key_secret_id")),
+	}
+}
+
+func (i *ImagePullerSecretResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*ClearBladeGoogleProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *ClearBladeGoogleProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	i.client = providerData.client
+}
+
+func (i *ImagePullerSecretResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data ImagePullerSecretResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	secretId := getSecretId(data.Namespace.ValueString(), data.Suffix.ValueString())
+	if err := createSecret(ctx, i.client, data.ProjectId.ValueString(), secretId); err != nil {
+		resp.Diagnostics.AddError("Failed to create secret", err.Error())
+		return
+	}
+	data.SecretId = types.StringValue(secretId)
+	if err := i.store(ctx, &data); err != nil {
+		resp.Diagnostics.AddError("Failed to store image puller secret", err.Error())
+		return
+	}
+	tflog.Trace(ctx, "created and stored image puller secret to GCP secrets")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (i *ImagePullerSecretResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data ImagePullerSecretResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	payload, err := getLatestSecretVersion(ctx, i.client, data.ProjectId.ValueString(), data.SecretId.ValueString())
+	if err != nil {
+		if isSecretNotFound(err) {
+			tflog.Warn(ctx, "Image puller secret not found, removing from state")
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.AddError("Failed to get image puller secret", err.Error())
+		return
+	}
+	data.setPayload(payload)
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (i *ImagePullerSecretResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data ImagePullerSecretResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := i.store(ctx, &data); err != nil {
+		resp.Diagnostics.AddError("Failed to store image puller secret", err.Error())
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (i *ImagePullerSecretResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data ImagePullerSecretResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := deleteSecret(ctx, i.client, data.ProjectId.ValueString(), data.SecretId.ValueString()); err != nil {
+		resp.Diagnostics.AddError("Failed to delete image puller secret", err.Error())
+		return
+	}
+}
+
+func (i *ImagePullerSecretResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to compare when the resource is created or destroyed.
+	if req.Plan.Raw.IsNull() || req.State.Raw.IsNull() {
+		return
+	}
+
+	var plan ImagePullerSecretResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	// A key passed directly changes the plan itself, and an unknown docker
+	// config is rebuilt anyway.
+	if plan.ServiceAccountKeySecretId.IsNull() || plan.ServiceAccountKeySecretId.IsUnknown() ||
+		plan.Registry.IsUnknown() || plan.DockerConfigJSON.IsUnknown() {
+		return
+	}
+
+	payload, err := i.build(ctx, &plan)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("service_account_key_secret_id"), "Failed to build docker config", err.Error())
+		return
+	}
+	if string(payload) == plan.DockerConfigJSON.ValueString() {
+		return
+	}
+	// The key in the referenced secret was rotated.
+	tflog.Info(ctx, "Service account key changed, rebuilding docker config")
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("docker_config_json"), types.StringUnknown())...)
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("helm_value"), types.StringUnknown())...)
+}
+
+// build returns the docker config for the service account key, reading the key
+// from the latest version of service_account_key_secret_id when set.
+func (i *ImagePullerSecretResource) build(ctx context.Context, data *ImagePullerSecretResourceModel) ([]byte, error) {
+	key := data.ServiceAccountKey.ValueString()
+	if !data.ServiceAccountKeySecretId.IsNull() {
+		payload, err := getLatestSecretVersion(ctx, i.client, data.ProjectId.ValueString(), data.ServiceAccountKeySecretId.ValueString())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get service account key secret: %w", err)
+		}
+		key = string(payload)
+	}
+	return buildDockerConfigJSON(data.Registry.ValueString(), key)
+}
+
+// store builds the docker config and adds it to the secret unless it is
+// unchanged.
+func (i *ImagePullerSecretResource) store(ctx context.Context, data *ImagePullerSecretResourceModel) error {
+	payload, err := i.build(ctx, data)
+	if err != nil {
+		return err
+	}
+	if err := addSecretVersionIfChanged(ctx, i.client, data.ProjectId.ValueString(), data.SecretId.ValueString(), payload); err != nil {
+		return fmt.Errorf("failed to add docker config to secret: %w", err)
+	}
+	data.setPayload(payload)
+	return nil
+}
+
+func (d *ImagePullerSecretResourceModel) setPayload(payload []byte) {
+	d.DockerConfigJSON = types.StringValue(string(payload))
+	d.HelmValue = types.StringValue(base64.StdEncoding.EncodeToString(payload))
+}
+
+// buildDockerConfigJSON returns the .dockerconfigjson authenticating to the
+// registry with a plain or base64 encoded service account JSON key.
+func buildDockerConfigJSON(registry, key string) ([]byte, error) {
+	key = strings.TrimSpace(key)
+	if !strings.HasPrefix(key, "{") {
+		decoded, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return nil, fmt.Errorf("service account key is neither JSON nor base64 encoded JSON")
+		}
+		key = strings.TrimSpace(string(decoded))
+	}
+	var account struct {
+		Type        string `json:"type"`
+		ClientEmail string `json:"client_email"`
+	}
+	if err := json.Unmarshal([]byte(key), &account); err != nil {
+		return nil, fmt.Errorf("failed to parse service account key: %w", err)
+	}
+	if account.Type != "service_account" || account.ClientEmail == "" {
+		return nil, fmt.Errorf("service account key is not a service account JSON key")
+	}
+
+	config := dockerConfig{Auths: map[string]dockerConfigAuth{
+		registry: {
+			Username: jsonKeyUsername,
+			Password: key,
+			Auth:     base64.StdEncoding.EncodeToString([]byte(jsonKeyUsername + ":" + key)),
+		},
+	}}
+	return json.Marshal(config)
+}
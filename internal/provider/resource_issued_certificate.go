@@ -0,0 +1,347 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int32planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &IssuedCertificateResource{}
+var _ resource.ResourceWithValidateConfig = &IssuedCertificateResource{}
+
+const defaultIssuedCertificateValidityDays = 365
+
+func NewIssuedCertificateResource() resource.Resource {
+	return &IssuedCertificateResource{}
+}
+
+// IssuedCertificateResource defines the resource implementation.
+type IssuedCertificateResource struct {
+	client *secretmanager.Client
+}
+
+// IssuedCertificateResourceModel describes the resource data model.
+type IssuedCertificateResourceModel struct {
+	ProjectId      types.String `tfsdk:"project_id"`
+	Namespace      types.String `tfsdk:"namespace"`
+	Suffix         types.String `tfsdk:"suffix"`
+	CASecretId     types.String `tfsdk:"ca_secret_id"`
+	CommonName     types.String `tfsdk:"common_name"`
+	DNSNames       types.List   `tfsdk:"dns_names"`
+	Usage          types.String `tfsdk:"usage"`
+	KeyAlgorithm   types.String `tfsdk:"key_algorithm"`
+	ValidityDays   types.Int32  `tfsdk:"validity_days"`
+	FileName       types.String `tfsdk:"file_name"`
+	SecretId       types.String `tfsdk:"secret_id"`
+	CertificatePEM types.String `tfsdk:"certificate_pem"`
+	CAChainPEM     types.String `tfsdk:"ca_chain_pem"`
+	NotAfter       types.String `tfsdk:"not_after"`
+}
+
+func (i *IssuedCertificateResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_issued_certificate"
+}
+
+func (i *IssuedCertificateResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Server or client certificate issued by a `clearblade-google_ca`. The private key, certificate and " +
+			"CA chain are stored in GCP Secrets in the same layout as `clearblade-google_tls_certificate`",
+
+		Attributes: map[string]schema.Attribute{
+			"project_id": schema.StringAttribute{
+				MarkdownDescription: "GCP project Id for storing the certificate. The CA secret must be in the same project",
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"namespace": schema.StringAttribute{
+				MarkdownDescription: "Instance namespace",
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"suffix": schema.StringAttribute{
+				MarkdownDescription: "Secret Id suffix",
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"ca_secret_id": schema.StringAttribute{
+				MarkdownDescription: "`secret_id` of the `clearblade-google_ca` issuing the certificate",
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"common_name": schema.StringAttribute{
+				MarkdownDescription: "Common name of the certificate",
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"dns_names": schema.ListAttribute{
+				MarkdownDescription: "DNS names and IP addresses of the certificate",
+				ElementType:         types.StringType,
+				Optional:            true,
+				PlanModifiers:       []planmodifier.List{listplanmodifier.RequiresReplace()},
+			},
+			"usage": schema.StringAttribute{
+				MarkdownDescription: "Certificate usage, either `server` (default) or `client`",
+				Optional:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"key_algorithm": schema.StringAttribute{
+				MarkdownDescription: "Private key algorithm, one of `RSA2048`, `RSA4096`, `ECDSA_P256` (default) or `ECDSA_P384`",
+				Optional:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"validity_days": schema.Int32Attribute{
+				MarkdownDescription: "Number of days the certificate is valid for, limited by the CA expiry. Defaults to 365",
+				Optional:            true,
+				PlanModifiers:       []planmodifier.Int32{int32planmodifier.RequiresReplace()},
+			},
+			"file_name": schema.StringAttribute{
+				MarkdownDescription: "File name of the bundle in the secret. Defaults to `<common_name>.pem`",
+				Optional:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"secret_id": schema.StringAttribute{
+				Computed: true,
+			},
+			"certificate_pem": schema.StringAttribute{
+				MarkdownDescription: "PEM encoded certificate",
+				Computed:            true,
+			},
+			"ca_chain_pem": schema.StringAttribute{
+				MarkdownDescription: "PEM encoded CA certificates in leaf-to-root order",
+				Computed:            true,
+			},
+			"not_after": schema.StringAttribute{
+				MarkdownDescription: "RFC 3339 expiry timestamp of the certificate",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (i *IssuedCertificateResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*ClearBladeGoogleProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *ClearBladeGoogleProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	i.client = providerData.client
+}
+
+func (i *IssuedCertificateResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data IssuedCertificateResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	caSecret, err := readCASecret(ctx, i.client, data.ProjectId.ValueString(), data.CASecretId.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read CA", err.Error())
+		return
+	}
+	ca, err := caSecret.issuer()
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to parse CA", err.Error())
+		return
+	}
+
+	var hosts []string
+	resp.Diagnostics.Append(data.DNSNames.ElementsAs(ctx, &hosts, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	usage := certificateUsageServer
+	if !data.Usage.IsNull() {
+		usage = certificateUsage(data.Usage.ValueString())
+	}
+	algorithm := keyAlgorithmECDSAP256
+	if !data.KeyAlgorithm.IsNull() {
+		algorithm = keyAlgorithm(data.KeyAlgorithm.ValueString())
+	}
+	validityDays := int32(defaultIssuedCertificateValidityDays)
+	if !data.ValidityDays.IsNull() {
+		validityDays = data.ValidityDays.ValueInt32()
+	}
+	keyPEM, certPEM, err := ca.issue(data.CommonName.ValueString(), hosts, usage, algorithm, time.Duration(validityDays)*24*time.Hour)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to issue certificate", err.Error())
+		return
+	}
+	payload, err := encodeCertificateSecret(map[string]string{
+		data.fileName(): keyPEM + certPEM + caSecret.chain(),
+	})
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to encode certificate", err.Error())
+		return
+	}
+
+	secretId := getSecretId(data.Namespace.ValueString(), data.Suffix.ValueString())
+	if err := createSecret(ctx, i.client, data.ProjectId.ValueString(), secretId); err != nil {
+		resp.Diagnostics.AddError("Failed to create secret", err.Error())
+		return
+	}
+	if err := addSecretVersion(ctx, i.client, data.ProjectId.ValueString(), secretId, payload); err != nil {
+		resp.Diagnostics.AddError("Failed to add certificate to secret", err.Error())
+		return
+	}
+	data.SecretId = types.StringValue(secretId)
+	if err := data.setCertificate(payload); err != nil {
+		resp.Diagnostics.AddError("Failed to parse certificate", err.Error())
+		return
+	}
+	tflog.Trace(ctx, "issued and stored certificate to GCP secrets")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (i *IssuedCertificateResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data IssuedCertificateResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	secretId := getSecretId(data.Namespace.ValueString(), data.Suffix.ValueString())
+	payload, err := getLatestSecretVersion(ctx, i.client, data.ProjectId.ValueString(), secretId)
+	if err != nil {
+		if isSecretNotFound(err) {
+			tflog.Warn(ctx, "Certificate secret not found, removing from state")
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.AddError("Failed to get certificate secret", err.Error())
+		return
+	}
+	data.SecretId = types.StringValue(secretId)
+	if err := data.setCertificate(payload); err != nil {
+		resp.Diagnostics.AddError("Failed to parse certificate", err.Error())
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (i *IssuedCertificateResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data, state IssuedCertificateResourceModel
+
+	// All configurable attributes require replacement, so only carry over the
+	// computed values.
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.SecretId = state.SecretId
+	data.CertificatePEM = state.CertificatePEM
+	data.CAChainPEM = state.CAChainPEM
+	data.NotAfter = state.NotAfter
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (i *IssuedCertificateResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data IssuedCertificateResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	secretId := getSecretId(data.Namespace.ValueString(), data.Suffix.ValueString())
+	resource := getSecretResourceName(data.ProjectId.ValueString(), secretId)
+	delReq := &secretmanagerpb.DeleteSecretRequest{
+		Name: resource,
+	}
+	if err := i.client.DeleteSecret(ctx, delReq); err != nil {
+		resp.Diagnostics.AddError("Failed to delete certificate", err.Error())
+		return
+	}
+}
+
+func (i *IssuedCertificateResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data IssuedCertificateResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !data.Usage.IsNull() && !data.Usage.IsUnknown() {
+		switch certificateUsage(data.Usage.ValueString()) {
+		case certificateUsageServer, certificateUsageClient:
+		default:
+			resp.Diagnostics.AddAttributeError(path.Root("usage"), "Invalid usage attribute",
+				fmt.Sprintf("usage must be %q or %q, got: %q", certificateUsageServer, certificateUsageClient, data.Usage.ValueString()))
+		}
+	}
+	if err := validateKeyAlgorithm(data.KeyAlgorithm); err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("key_algorithm"), "Invalid key_algorithm attribute", err.Error())
+	}
+	if !data.ValidityDays.IsNull() && !data.ValidityDays.IsUnknown() && data.ValidityDays.ValueInt32() < 1 {
+		resp.Diagnostics.AddAttributeError(path.Root("validity_days"), "Invalid validity_days attribute", "validity_days must be at least 1")
+	}
+}
+
+func (d *IssuedCertificateResourceModel) fileName() string {
+	if !d.FileName.IsNull() {
+		return d.FileName.ValueString()
+	}
+	return d.CommonName.ValueString() + ".pem"
+}
+
+// setCertificate sets the computed attributes from the stored bundle.
+func (d *IssuedCertificateResourceModel) setCertificate(payload []byte) error {
+	certs, err := decodeCertificateSecret(payload)
+	if err != nil {
+		return err
+	}
+	data, ok := certs[d.fileName()]
+	if !ok {
+		return fmt.Errorf("certificate %s not found in secret", d.fileName())
+	}
+	bundle, err := parseCertificateBundle(data)
+	if err != nil {
+		return err
+	}
+	caChain := ""
+	for _, cert := range bundle.chain[1:] {
+		caChain += encodeCertificatePEM(cert.Raw)
+	}
+	d.CertificatePEM = types.StringValue(encodeCertificatePEM(bundle.leaf().Raw))
+	d.CAChainPEM = types.StringValue(caChain)
+	d.NotAfter = types.StringValue(bundle.leaf().NotAfter.UTC().Format(time.RFC3339))
+	return nil
+}
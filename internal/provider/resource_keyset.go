@@ -0,0 +1,255 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	"github.com/google/tink/go/insecurecleartextkeyset"
+	"github.com/google/tink/go/keyset"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// keysetResource implements the resources storing a Tink keyset created from a
+// key template in GCP Secrets, which only differ in their algorithms and the
+// attributes exported from the keyset.
+type keysetResource struct {
+	client *secretmanager.Client
+	// kind names the keyset in diagnostics, like "signing keyset".
+	kind      string
+	templates map[string]func() *tinkpb.KeyTemplate
+	// defaultAlgorithm is used when algorithm is not set, empty when it is required.
+	defaultAlgorithm string
+	newModel         func() keysetModel
+}
+
+// keysetModel is the data model of a keyset resource.
+type keysetModel interface {
+	keyset() *KeysetResourceModel
+	// setKeyset sets the attributes exported from the keyset.
+	setKeyset(kh *keyset.Handle) error
+}
+
+// KeysetResourceModel describes the attributes shared by the keyset resources.
+type KeysetResourceModel struct {
+	ProjectId types.String `tfsdk:"project_id"`
+	Namespace types.String `tfsdk:"namespace"`
+	Suffix    types.String `tfsdk:"suffix"`
+	Algorithm types.String `tfsdk:"algorithm"`
+	Format    types.String `tfsdk:"format"`
+	SecretId  types.String `tfsdk:"secret_id"`
+	Key       types.String `tfsdk:"key"`
+}
+
+// keysetAttributes returns the schema attributes shared by the keyset resources.
+func keysetAttributes(algorithm schema.StringAttribute) map[string]schema.Attribute {
+	return map[string]schema.Attribute{
+		"project_id": schema.StringAttribute{
+			MarkdownDescription: "GCP project Id for storing the keyset",
+			Required:            true,
+			PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+		},
+		"namespace": schema.StringAttribute{
+			MarkdownDescription: "Instance namespace",
+			Required:            true,
+			PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+		},
+		"suffix": schema.StringAttribute{
+			MarkdownDescription: "Secret Id suffix",
+			Required:            true,
+			PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+		},
+		"algorithm": algorithm,
+		"format": schema.StringAttribute{
+			MarkdownDescription: "Serialization format of the stored keyset, either `json` (default) or `binary` protobuf. " +
+				"Changing the format rewrites the existing keyset in the new format",
+			Optional:   true,
+			Validators: []validator.String{stringvalidator.OneOf(string(keysetFormatJSON), string(keysetFormatBinary))},
+		},
+		"secret_id": schema.StringAttribute{
+			Computed: true,
+		},
+		"key": schema.StringAttribute{
+			Computed: true,
+		},
+	}
+}
+
+func (r *keysetResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*ClearBladeGoogleProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *ClearBladeGoogleProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.client = providerData.client
+}
+
+func (r *keysetResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	data := r.newModel()
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	config := r.keysetConfig(data.keyset())
+	if err := createSecret(ctx, r.client, config.projectId.ValueString(), config.secretId()); err != nil {
+		resp.Diagnostics.AddError("Failed to create secret", err.Error())
+		return
+	}
+	kh, err := keyset.NewHandle(r.templates[config.origin.ValueString()]())
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to create new "+r.kind, err.Error())
+		return
+	}
+	if err := insecurecleartextkeyset.Write(kh, config.secretKeyset(ctx, r.client)); err != nil {
+		resp.Diagnostics.AddError("Failed to write "+r.kind+" to GCP Secrets", err.Error())
+		return
+	}
+	if err := r.setKeyset(data, config, kh); err != nil {
+		resp.Diagnostics.AddError("Failed to export "+r.kind, err.Error())
+		return
+	}
+	tflog.Trace(ctx, "created and stored "+r.kind+" to GCP secrets")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+}
+
+func (r *keysetResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	data := r.newModel()
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	config := r.keysetConfig(data.keyset())
+	kh, err := config.storedKeyset(ctx, r.client)
+	if err != nil {
+		if isSecretNotFound(err) {
+			tflog.Warn(ctx, "Keyset secret not found, removing from state")
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.AddError("Failed to read "+r.kind, err.Error())
+		return
+	}
+	if d := data.keyset(); d.Algorithm.IsNull() && r.defaultAlgorithm == "" {
+		// Imported keysets take the algorithm of their primary key
+		d.Algorithm = types.StringValue(keysetAlgorithm(kh, r.templates))
+	}
+	if err := r.setKeyset(data, config, kh); err != nil {
+		resp.Diagnostics.AddError("Failed to export "+r.kind, err.Error())
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+}
+
+func (r *keysetResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	data, state := r.newModel(), r.newModel()
+
+	// Read Terraform plan and prior state data into the models
+	resp.Diagnostics.Append(req.Plan.Get(ctx, data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	config := r.keysetConfig(data.keyset())
+	kh, err := rotateSecretKeyset(ctx, r.client, r.keysetConfig(state.keyset()), config, r.templates[config.origin.ValueString()])
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to update "+r.kind, err.Error())
+		return
+	}
+	if err := r.setKeyset(data, config, kh); err != nil {
+		resp.Diagnostics.AddError("Failed to export "+r.kind, err.Error())
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+}
+
+func (r *keysetResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	data := r.newModel()
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	config := r.keysetConfig(data.keyset())
+	if err := deleteSecret(ctx, r.client, config.projectId.ValueString(), config.secretId()); err != nil {
+		resp.Diagnostics.AddError("Failed to delete "+r.kind, err.Error())
+		return
+	}
+}
+
+// ImportState imports a keyset by `<project_id>/<namespace>/<suffix>`.
+func (r *keysetResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	parts := strings.Split(req.ID, "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+		resp.Diagnostics.AddError("Invalid import Id", fmt.Sprintf("expected <project_id>/<namespace>/<suffix>, got: %q", req.ID))
+		return
+	}
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("project_id"), parts[0])...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("namespace"), parts[1])...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("suffix"), parts[2])...)
+}
+
+// keysetConfig returns the configuration of the keyset secret, keys are created
+// from the algorithm.
+func (r *keysetResource) keysetConfig(d *KeysetResourceModel) keysetConfig {
+	algorithm := d.Algorithm
+	if algorithm.IsNull() {
+		algorithm = types.StringValue(r.defaultAlgorithm)
+	}
+	return keysetConfig{
+		projectId: d.ProjectId,
+		namespace: d.Namespace,
+		suffix:    d.Suffix,
+		origin:    algorithm,
+		format:    d.Format,
+	}
+}
+
+// setKeyset sets the computed attributes of the stored keyset.
+func (r *keysetResource) setKeyset(data keysetModel, config keysetConfig, kh *keyset.Handle) error {
+	if err := data.setKeyset(kh); err != nil {
+		return err
+	}
+	data.keyset().Key = types.StringValue(kh.String())
+	data.keyset().SecretId = types.StringValue(config.secretId())
+	return nil
+}
+
+func (d *KeysetResourceModel) keyset() *KeysetResourceModel {
+	return d
+}
@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/google/tink/go/keyset"
+	"github.com/google/tink/go/mac"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &MACKeysetResource{}
+var _ resource.ResourceWithImportState = &MACKeysetResource{}
+
+const defaultMACAlgorithm = "HMAC_SHA256"
+
+// macKeyTemplates maps the supported algorithms to their key templates.
+var macKeyTemplates = map[string]func() *tinkpb.KeyTemplate{
+	defaultMACAlgorithm: hmacSHA256RawKeyTemplate,
+}
+
+func NewMACKeysetResource() resource.Resource {
+	return &MACKeysetResource{keysetResource{
+		kind:             "MAC keyset",
+		templates:        macKeyTemplates,
+		defaultAlgorithm: defaultMACAlgorithm,
+		newModel:         func() keysetModel { return &MACKeysetResourceModel{} },
+	}}
+}
+
+// MACKeysetResource defines the resource implementation.
+type MACKeysetResource struct {
+	keysetResource
+}
+
+// MACKeysetResourceModel describes the resource data model.
+type MACKeysetResourceModel struct {
+	KeysetResourceModel
+}
+
+func (m *MACKeysetResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_mac_keyset"
+}
+
+func (m *MACKeysetResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Tink MAC keyset, for example for signing ClearBlade webhooks. The keyset is stored in GCP Secrets. " +
+			"Import with `<project_id>/<namespace>/<suffix>`",
+
+		Attributes: keysetAttributes(schema.StringAttribute{
+			MarkdownDescription: "MAC algorithm. Only `HMAC_SHA256` (default) is supported. Tags are plain HMAC-SHA256 without a Tink key prefix",
+			Optional:            true,
+			Validators:          []validator.String{stringvalidator.OneOf(keyTemplateNames(macKeyTemplates)...)},
+		}),
+	}
+}
+
+// setKeyset is a no-op, as MAC keysets only export the keyset info.
+func (d *MACKeysetResourceModel) setKeyset(kh *keyset.Handle) error {
+	return nil
+}
+
+// hmacSHA256RawKeyTemplate is the HMAC-SHA256 template with 256-bit tags and the
+// RAW output prefix, so tags can be verified by consumers without Tink.
+func hmacSHA256RawKeyTemplate() *tinkpb.KeyTemplate {
+	template := mac.HMACSHA256Tag256KeyTemplate()
+	template.OutputPrefixType = tinkpb.OutputPrefixType_RAW
+	return template
+}
@@ -3,18 +3,27 @@ package provider
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
 	"fmt"
+	"strings"
 
 	secretmanager "cloud.google.com/go/secretmanager/apiv1"
 	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
 	"github.com/google/tink/go/aead"
 	"github.com/google/tink/go/insecurecleartextkeyset"
 	"github.com/google/tink/go/keyset"
+	aesgcmpb "github.com/google/tink/go/proto/aes_gcm_go_proto"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"google.golang.org/protobuf/proto"
 )
 
 // Ensure provider defined types fully satisfy framework interfaces.
@@ -35,6 +44,8 @@ type MEKResourceModel struct {
 	ProjectId types.String `tfsdk:"project_id"`
 	Namespace types.String `tfsdk:"namespace"`
 	Suffix    types.String `tfsdk:"suffix"`
+	Source    types.String `tfsdk:"source"`
+	Format    types.String `tfsdk:"format"`
 	SecretId  types.String `tfsdk:"secret_id"`
 	Key       types.String `tfsdk:"key"`
 }
@@ -61,6 +72,19 @@ func (m *MEKResource) Schema(ctx context.Context, req resource.SchemaRequest, re
 				MarkdownDescription: "Secret Id suffix",
 				Required:            true,
 			},
+			"source": schema.StringAttribute{
+				MarkdownDescription: "Existing key to import instead of generating a new one. Accepts a base64 encoded raw 256-bit AES key, " +
+					"a Tink JSON keyset, a base64 encoded Tink binary keyset or a Secret Manager secret version name " +
+					"(`projects/<project>/secrets/<secret>/versions/<version>`) holding any of those",
+				Optional:  true,
+				Sensitive: true,
+			},
+			"format": schema.StringAttribute{
+				MarkdownDescription: "Serialization format of the stored keyset, either `json` (default) or `binary` protobuf. " +
+					"Changing the format rewrites the existing keyset in the new format",
+				Optional:   true,
+				Validators: []validator.String{stringvalidator.OneOf(string(keysetFormatJSON), string(keysetFormatBinary))},
+			},
 			"secret_id": schema.StringAttribute{
 				Computed: true,
 			},
@@ -77,18 +101,18 @@ func (m *MEKResource) Configure(ctx context.Context, req resource.ConfigureReque
 		return
 	}
 
-	client, ok := req.ProviderData.(*secretmanager.Client)
+	providerData, ok := req.ProviderData.(*ClearBladeGoogleProviderData)
 
 	if !ok {
 		resp.Diagnostics.AddError(
 			"Unexpected Resource Configure Type",
-			fmt.Sprintf("Expected *secretmanager.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected *ClearBladeGoogleProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 
 		return
 	}
 
-	m.client = client
+	m.client = providerData.client
 }
 
 func (m *MEKResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
@@ -107,18 +131,12 @@ func (m *MEKResource) Create(ctx context.Context, req resource.CreateRequest, re
 		return
 	}
 	data.SecretId = types.StringValue(secretId)
-	kh, err := keyset.NewHandle(aead.AES256GCMKeyTemplate())
+	kh, err := m.newMEKHandle(ctx, &data)
 	if err != nil {
 		resp.Diagnostics.AddError("Failed to create new MEK", err.Error())
 		return
 	}
-	mrw := &mekGCPReaderWriter{
-		ctx:       ctx,
-		client:    m.client,
-		projectId: data.ProjectId.ValueString(),
-		secretId:  secretId,
-	}
-	w := keyset.NewJSONWriter(mrw)
+	w := data.keysetConfig().secretKeyset(ctx, m.client)
 	if err := insecurecleartextkeyset.Write(kh, w); err != nil {
 		resp.Diagnostics.AddError("Failed to write MEK to GCP Secrets", err.Error())
 		return
@@ -143,17 +161,13 @@ func (m *MEKResource) Read(ctx context.Context, req resource.ReadRequest, resp *
 	}
 
 	secretId := getSecretId(data.Namespace.ValueString(), data.Suffix.ValueString())
-	resource := getSecretResourceName(data.ProjectId.ValueString(), secretId) + "/versions/latest"
-	secReq := &secretmanagerpb.AccessSecretVersionRequest{
-		Name: resource,
-	}
-	rval, err := m.client.AccessSecretVersion(ctx, secReq)
-	if err != nil {
-		resp.Diagnostics.AddError("Faled to get MEK secret", err.Error())
-		return
-	}
-	kh, err := insecurecleartextkeyset.Read(keyset.NewJSONReader(bytes.NewReader(rval.Payload.Data)))
+	kh, err := data.keysetConfig().secretKeyset(ctx, m.client).handle()
 	if err != nil {
+		if isSecretNotFound(err) {
+			tflog.Warn(ctx, "MEK secret not found, removing from state")
+			resp.State.RemoveResource(ctx)
+			return
+		}
 		resp.Diagnostics.AddError("Failed to read MEK", err.Error())
 		return
 	}
@@ -165,35 +179,26 @@ func (m *MEKResource) Read(ctx context.Context, req resource.ReadRequest, resp *
 }
 
 func (m *MEKResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
-	var data MEKResourceModel
+	var data, state MEKResourceModel
 
-	// Read Terraform plan data into the model
+	// Read Terraform plan and prior state data into the models
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
 
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	secretId := getSecretId(data.Namespace.ValueString(), data.Suffix.ValueString())
-	kh, err := keyset.NewHandle(aead.AES256GCMKeyTemplate())
+	kh, err := updateSecretKeyset(ctx, m.client, state.keysetConfig(), data.keysetConfig(), func() (*keyset.Handle, error) {
+		return m.newMEKHandle(ctx, &data)
+	})
 	if err != nil {
-		resp.Diagnostics.AddError("Failed to create new MEK", err.Error())
-		return
-	}
-	mrw := &mekGCPReaderWriter{
-		ctx:       ctx,
-		client:    m.client,
-		projectId: data.ProjectId.ValueString(),
-		secretId:  secretId,
-	}
-	w := keyset.NewJSONWriter(mrw)
-	if err := insecurecleartextkeyset.Write(kh, w); err != nil {
-		resp.Diagnostics.AddError("Failed to write MEK to GCP Secrets", err.Error())
+		resp.Diagnostics.AddError("Failed to update MEK", err.Error())
 		return
 	}
 
 	data.Key = types.StringValue(kh.String())
-	data.SecretId = types.StringValue(secretId)
+	data.SecretId = types.StringValue(data.keysetConfig().secretId())
 	// Save updated data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
@@ -223,22 +228,108 @@ func (m *MEKResource) ImportState(ctx context.Context, req resource.ImportStateR
 	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
 }
 
+// newMEKHandle returns the keyset to store as the MEK. The keyset is imported from
+// source when it is set and freshly generated otherwise.
+func (m *MEKResource) newMEKHandle(ctx context.Context, data *MEKResourceModel) (*keyset.Handle, error) {
+	source := strings.TrimSpace(data.Source.ValueString())
+	if source == "" {
+		return keyset.NewHandle(aead.AES256GCMKeyTemplate())
+	}
+	if !strings.HasPrefix(source, "projects/") {
+		return parseMEK([]byte(source))
+	}
+	if !strings.Contains(source, "/versions/") {
+		source += "/versions/latest"
+	}
+	rval, err := m.client.AccessSecretVersion(ctx, &secretmanagerpb.AccessSecretVersionRequest{Name: source})
+	if err != nil {
+		return nil, fmt.Errorf("failed to access source secret version: %w", err)
+	}
+	return parseMEK(rval.Payload.Data)
+}
+
+// keysetConfig returns the configuration of the MEK secret.
+func (d *MEKResourceModel) keysetConfig() keysetConfig {
+	return keysetConfig{
+		projectId: d.ProjectId,
+		namespace: d.Namespace,
+		suffix:    d.Suffix,
+		origin:    d.Source,
+		format:    d.Format,
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
-type mekGCPReaderWriter struct {
-	ctx       context.Context
-	client    *secretmanager.Client
-	projectId string
-	secretId  string
+// readMEKHandle reads the MEK keyset from the latest version of the given secret,
+// detecting whether it is stored as JSON or binary.
+func readMEKHandle(ctx context.Context, client *secretmanager.Client, projectId, secretId string) (*keyset.Handle, error) {
+	r := &secretKeysetReaderWriter{
+		ctx:       ctx,
+		client:    client,
+		projectId: projectId,
+		secretId:  secretId,
+	}
+	return r.handle()
 }
 
-func (m *mekGCPReaderWriter) Read(p []byte) (n int, err error) {
-	return 0, nil
+// parseMEK converts an existing key into a keyset handle. b may hold a Tink JSON
+// keyset, a Tink binary keyset or a raw 256-bit AES-GCM key, the latter two either
+// as is or base64 encoded.
+func parseMEK(b []byte) (*keyset.Handle, error) {
+	var kh *keyset.Handle
+	var err error
+	if len(b) == 32 {
+		// A raw key is not trimmed, as its first or last bytes may be whitespace
+		kh, err = rawAESGCMKeysetHandle(b)
+	} else if b = bytes.TrimSpace(b); bytes.HasPrefix(b, []byte("{")) {
+		kh, err = insecurecleartextkeyset.Read(keyset.NewJSONReader(bytes.NewReader(b)))
+	} else {
+		if decoded, decodeErr := base64.StdEncoding.DecodeString(string(b)); decodeErr == nil {
+			b = decoded
+		}
+		if len(b) == 32 {
+			kh, err = rawAESGCMKeysetHandle(b)
+		} else {
+			kh, err = insecurecleartextkeyset.Read(keyset.NewBinaryReader(bytes.NewReader(b)))
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key: %w", err)
+	}
+	if _, err := aead.New(kh); err != nil {
+		return nil, fmt.Errorf("key is not a valid AEAD keyset: %w", err)
+	}
+	return kh, nil
 }
 
-func (m *mekGCPReaderWriter) Write(p []byte) (n int, err error) {
-	if err := addSecretVersion(m.ctx, m.client, m.projectId, m.secretId, p); err != nil {
-		return -1, err
+// rawAESGCMKeysetHandle wraps a raw AES-GCM key in a single key keyset. The key
+// uses the RAW output prefix so that ciphertexts produced before the import, which
+// carry no Tink key id prefix, can still be decrypted.
+func rawAESGCMKeysetHandle(key []byte) (*keyset.Handle, error) {
+	serialized, err := proto.Marshal(&aesgcmpb.AesGcmKey{Version: 0, KeyValue: key})
+	if err != nil {
+		return nil, err
+	}
+	var id [4]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, err
+	}
+	keyId := binary.BigEndian.Uint32(id[:])
+	ks := &tinkpb.Keyset{
+		PrimaryKeyId: keyId,
+		Key: []*tinkpb.Keyset_Key{
+			{
+				KeyData: &tinkpb.KeyData{
+					TypeUrl:         "type.googleapis.com/google.crypto.tink.AesGcmKey",
+					Value:           serialized,
+					KeyMaterialType: tinkpb.KeyData_SYMMETRIC,
+				},
+				Status:           tinkpb.KeyStatusType_ENABLED,
+				KeyId:            keyId,
+				OutputPrefixType: tinkpb.OutputPrefixType_RAW,
+			},
+		},
 	}
-	return len(p), nil
+	return insecurecleartextkeyset.Read(&keyset.MemReaderWriter{Keyset: ks})
 }
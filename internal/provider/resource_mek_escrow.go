@@ -0,0 +1,239 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	"github.com/google/tink/go/hybrid"
+	"github.com/google/tink/go/insecurecleartextkeyset"
+	"github.com/google/tink/go/keyset"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &MEKEscrowResource{}
+var _ resource.ResourceWithModifyPlan = &MEKEscrowResource{}
+
+func NewMEKEscrowResource() resource.Resource {
+	return &MEKEscrowResource{}
+}
+
+// MEKEscrowResource defines the resource implementation.
+type MEKEscrowResource struct {
+	client *secretmanager.Client
+}
+
+// MEKEscrowResourceModel describes the resource data model.
+type MEKEscrowResourceModel struct {
+	ProjectId             types.String `tfsdk:"project_id"`
+	Namespace             types.String `tfsdk:"namespace"`
+	Suffix                types.String `tfsdk:"suffix"`
+	RecipientPublicKeyset types.String `tfsdk:"recipient_public_keyset"`
+	ContextInfo           types.String `tfsdk:"context_info"`
+	SecretId              types.String `tfsdk:"secret_id"`
+	Key                   types.String `tfsdk:"key"`
+	Ciphertext            types.String `tfsdk:"ciphertext"`
+}
+
+func (m *MEKEscrowResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_mek_escrow"
+}
+
+func (m *MEKEscrowResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Escrow copy of the ClearBlade Master Encryption Key encrypted to a recipient public key. " +
+			"The MEK keyset is encrypted with Tink hybrid encryption so it never appears in the state in cleartext. " +
+			"The escrow is replaced whenever the MEK changes.",
+
+		Attributes: map[string]schema.Attribute{
+			"project_id": schema.StringAttribute{
+				MarkdownDescription: "GCP project Id the MEK is stored in",
+				Required:            true,
+			},
+			"namespace": schema.StringAttribute{
+				MarkdownDescription: "Instance namespace",
+				Required:            true,
+			},
+			"suffix": schema.StringAttribute{
+				MarkdownDescription: "MEK secret Id suffix",
+				Required:            true,
+			},
+			"recipient_public_keyset": schema.StringAttribute{
+				MarkdownDescription: "Tink JSON public keyset of the recipient, for example one created with " +
+					"`tinkey create-public-keyset` from a `DHKEM_X25519_HKDF_SHA256_HKDF_SHA256_AES_256_GCM` private keyset",
+				Required: true,
+			},
+			"context_info": schema.StringAttribute{
+				MarkdownDescription: "Context info bound to the ciphertext. The same value must be supplied when decrypting",
+				Optional:            true,
+			},
+			"secret_id": schema.StringAttribute{
+				Computed: true,
+			},
+			"key": schema.StringAttribute{
+				MarkdownDescription: "Keyset info of the escrowed MEK",
+				Computed:            true,
+			},
+			"ciphertext": schema.StringAttribute{
+				MarkdownDescription: "Base64 encoded hybrid ciphertext of the MEK JSON keyset",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (m *MEKEscrowResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*ClearBladeGoogleProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *ClearBladeGoogleProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	m.client = providerData.client
+}
+
+func (m *MEKEscrowResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data MEKEscrowResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := m.escrow(ctx, &data); err != nil {
+		resp.Diagnostics.AddError("Failed to escrow MEK", err.Error())
+		return
+	}
+	tflog.Trace(ctx, "created MEK escrow")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (m *MEKEscrowResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data MEKEscrowResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// A changed MEK is detected in ModifyPlan, which replaces the stale escrow.
+	if _, err := readMEKHandle(ctx, m.client, data.ProjectId.ValueString(), data.SecretId.ValueString()); err != nil {
+		if isSecretNotFound(err) {
+			tflog.Warn(ctx, "MEK secret not found, removing escrow from state")
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.AddError("Failed to read MEK", err.Error())
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (m *MEKEscrowResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data MEKEscrowResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := m.escrow(ctx, &data); err != nil {
+		resp.Diagnostics.AddError("Failed to escrow MEK", err.Error())
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (m *MEKEscrowResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	// The escrow only lives in the state, there is nothing to clean up.
+}
+
+func (m *MEKEscrowResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to compare when the resource is created or destroyed.
+	if req.Plan.Raw.IsNull() || req.State.Raw.IsNull() {
+		return
+	}
+
+	var state MEKEscrowResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	kh, err := readMEKHandle(ctx, m.client, state.ProjectId.ValueString(), state.SecretId.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read MEK", err.Error())
+		return
+	}
+	if kh.String() == state.Key.ValueString() {
+		return
+	}
+	// The MEK was rotated or replaced, so the escrowed copy is stale.
+	tflog.Info(ctx, "MEK changed since it was escrowed, replacing escrow")
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("key"), types.StringUnknown())...)
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("ciphertext"), types.StringUnknown())...)
+	resp.RequiresReplace = append(resp.RequiresReplace, path.Root("key"))
+}
+
+func (m *MEKEscrowResource) escrow(ctx context.Context, data *MEKEscrowResourceModel) error {
+	secretId := getSecretId(data.Namespace.ValueString(), data.Suffix.ValueString())
+	kh, err := readMEKHandle(ctx, m.client, data.ProjectId.ValueString(), secretId)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := insecurecleartextkeyset.Write(kh, keyset.NewJSONWriter(&buf)); err != nil {
+		return fmt.Errorf("failed to serialize MEK: %w", err)
+	}
+	ciphertext, err := hybridEncrypt(data.RecipientPublicKeyset.ValueString(), buf.Bytes(), []byte(data.ContextInfo.ValueString()))
+	if err != nil {
+		return err
+	}
+	data.SecretId = types.StringValue(secretId)
+	data.Key = types.StringValue(kh.String())
+	data.Ciphertext = types.StringValue(base64.StdEncoding.EncodeToString(ciphertext))
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// hybridEncrypt encrypts plaintext to the given Tink JSON public keyset.
+func hybridEncrypt(publicKeyset string, plaintext, contextInfo []byte) ([]byte, error) {
+	pub, err := keyset.ReadWithNoSecrets(keyset.NewJSONReader(strings.NewReader(publicKeyset)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recipient public keyset: %w", err)
+	}
+	enc, err := hybrid.NewHybridEncrypt(pub)
+	if err != nil {
+		return nil, fmt.Errorf("recipient public keyset is not a hybrid encryption keyset: %w", err)
+	}
+	return enc.Encrypt(plaintext, contextInfo)
+}
@@ -0,0 +1,349 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/google/tink/go/insecurecleartextkeyset"
+	"github.com/google/tink/go/keyset"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int32validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &MEKSharesResource{}
+var _ resource.ResourceWithValidateConfig = &MEKSharesResource{}
+var _ resource.ResourceWithModifyPlan = &MEKSharesResource{}
+
+func NewMEKSharesResource() resource.Resource {
+	return &MEKSharesResource{}
+}
+
+// MEKSharesResource defines the resource implementation.
+type MEKSharesResource struct {
+	client *secretmanager.Client
+}
+
+// MEKSharesResourceModel describes the resource data model.
+type MEKSharesResourceModel struct {
+	ProjectId              types.String `tfsdk:"project_id"`
+	Namespace              types.String `tfsdk:"namespace"`
+	Suffix                 types.String `tfsdk:"suffix"`
+	Threshold              types.Int32  `tfsdk:"threshold"`
+	CustodianPublicKeysets types.List   `tfsdk:"custodian_public_keysets"`
+	StoreAsSecrets         types.Bool   `tfsdk:"store_as_secrets"`
+	SecretId               types.String `tfsdk:"secret_id"`
+	Key                    types.String `tfsdk:"key"`
+	Shares                 types.List   `tfsdk:"shares"`
+	ShareSecretIds         types.List   `tfsdk:"share_secret_ids"`
+}
+
+func (m *MEKSharesResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_mek_shares"
+}
+
+func (m *MEKSharesResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Splits the ClearBlade Master Encryption Key into Shamir secret shares for break-glass recovery. " +
+			"One share is created per custodian and encrypted to the custodian's Tink hybrid public keyset. " +
+			"Any `threshold` decrypted shares can be recombined offline with the `combine_mek_shares` provider function. " +
+			"The shares are replaced whenever the MEK changes.",
+
+		Attributes: map[string]schema.Attribute{
+			"project_id": schema.StringAttribute{
+				MarkdownDescription: "GCP project Id the MEK is stored in",
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"namespace": schema.StringAttribute{
+				MarkdownDescription: "Instance namespace",
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"suffix": schema.StringAttribute{
+				MarkdownDescription: "MEK secret Id suffix",
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"threshold": schema.Int32Attribute{
+				MarkdownDescription: "Number of shares required to reconstruct the MEK, between 2 and the number of custodians",
+				Required:            true,
+				Validators:          []validator.Int32{int32validator.Between(2, 255)},
+			},
+			"custodian_public_keysets": schema.ListAttribute{
+				ElementType:         types.StringType,
+				MarkdownDescription: "Tink JSON hybrid public keysets, one per custodian. The number of shares equals the number of custodians, at most 255",
+				Required:            true,
+				Validators:          []validator.List{listvalidator.SizeBetween(2, 255)},
+			},
+			"store_as_secrets": schema.BoolAttribute{
+				MarkdownDescription: "Also store each encrypted share in its own secret named `<namespace><suffix>-share-<n>`",
+				Optional:            true,
+			},
+			"secret_id": schema.StringAttribute{
+				Computed: true,
+			},
+			"key": schema.StringAttribute{
+				MarkdownDescription: "Keyset info of the split MEK",
+				Computed:            true,
+			},
+			"shares": schema.ListAttribute{
+				ElementType:         types.StringType,
+				MarkdownDescription: "Base64 encoded encrypted shares, in the same order as `custodian_public_keysets`",
+				Computed:            true,
+			},
+			"share_secret_ids": schema.ListAttribute{
+				ElementType:         types.StringType,
+				MarkdownDescription: "Secret Ids holding the encrypted shares when `store_as_secrets` is set",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (m *MEKSharesResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data MEKSharesResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.Threshold.IsNull() || data.Threshold.IsUnknown() || data.CustodianPublicKeysets.IsNull() || data.CustodianPublicKeysets.IsUnknown() {
+		return
+	}
+	if custodians := len(data.CustodianPublicKeysets.Elements()); int(data.Threshold.ValueInt32()) > custodians {
+		resp.Diagnostics.AddAttributeError(path.Root("threshold"), "Invalid threshold attribute",
+			fmt.Sprintf("threshold %d is more than the number of custodians (%d)", data.Threshold.ValueInt32(), custodians))
+	}
+}
+
+func (m *MEKSharesResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*ClearBladeGoogleProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *ClearBladeGoogleProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	m.client = providerData.client
+}
+
+func (m *MEKSharesResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data MEKSharesResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := m.split(ctx, &data); err != nil {
+		resp.Diagnostics.AddError("Failed to split MEK", err.Error())
+		return
+	}
+	tflog.Trace(ctx, "created MEK shares")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (m *MEKSharesResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data MEKSharesResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// A changed MEK is detected in ModifyPlan, which replaces the stale shares.
+	if _, err := readMEKHandle(ctx, m.client, data.ProjectId.ValueString(), data.SecretId.ValueString()); err != nil {
+		if isSecretNotFound(err) {
+			tflog.Warn(ctx, "MEK secret not found, removing shares from state")
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.AddError("Failed to read MEK", err.Error())
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (m *MEKSharesResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data, state MEKSharesResourceModel
+
+	// Read Terraform plan and prior state data into the models
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := m.split(ctx, &data); err != nil {
+		resp.Diagnostics.AddError("Failed to split MEK", err.Error())
+		return
+	}
+
+	// Remove share secrets which are no longer part of the split
+	var oldIds, newIds []string
+	resp.Diagnostics.Append(state.ShareSecretIds.ElementsAs(ctx, &oldIds, false)...)
+	resp.Diagnostics.Append(data.ShareSecretIds.ElementsAs(ctx, &newIds, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	kept := map[string]bool{}
+	for _, id := range newIds {
+		kept[id] = true
+	}
+	for _, id := range oldIds {
+		if kept[id] {
+			continue
+		}
+		if err := m.deleteShareSecret(ctx, data.ProjectId.ValueString(), id); err != nil {
+			resp.Diagnostics.AddError("Failed to delete MEK share", err.Error())
+			return
+		}
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (m *MEKSharesResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data MEKSharesResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var ids []string
+	resp.Diagnostics.Append(data.ShareSecretIds.ElementsAs(ctx, &ids, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	for _, id := range ids {
+		if err := m.deleteShareSecret(ctx, data.ProjectId.ValueString(), id); err != nil {
+			resp.Diagnostics.AddError("Failed to delete MEK share", err.Error())
+			return
+		}
+	}
+}
+
+func (m *MEKSharesResource) split(ctx context.Context, data *MEKSharesResourceModel) error {
+	var custodians []string
+	if diags := data.CustodianPublicKeysets.ElementsAs(ctx, &custodians, false); diags.HasError() {
+		return fmt.Errorf("failed to read custodian public keysets")
+	}
+	secretId := getSecretId(data.Namespace.ValueString(), data.Suffix.ValueString())
+	kh, err := readMEKHandle(ctx, m.client, data.ProjectId.ValueString(), secretId)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := insecurecleartextkeyset.Write(kh, keyset.NewJSONWriter(&buf)); err != nil {
+		return fmt.Errorf("failed to serialize MEK: %w", err)
+	}
+	shares, err := splitSecret(buf.Bytes(), len(custodians), int(data.Threshold.ValueInt32()))
+	if err != nil {
+		return err
+	}
+
+	encrypted := make([]string, len(shares))
+	shareSecretIds := []string{}
+	for i, share := range shares {
+		ciphertext, err := hybridEncrypt(custodians[i], share, nil)
+		if err != nil {
+			return fmt.Errorf("custodian %d: %w", i, err)
+		}
+		encrypted[i] = base64.StdEncoding.EncodeToString(ciphertext)
+		if !data.StoreAsSecrets.ValueBool() {
+			continue
+		}
+		shareSecretId := secretId + "-share-" + strconv.Itoa(i+1)
+		if err := createSecret(ctx, m.client, data.ProjectId.ValueString(), shareSecretId); err != nil {
+			return fmt.Errorf("failed to create share secret: %w", err)
+		}
+		if err := addSecretVersion(ctx, m.client, data.ProjectId.ValueString(), shareSecretId, ciphertext); err != nil {
+			return fmt.Errorf("failed to add share to secret: %w", err)
+		}
+		shareSecretIds = append(shareSecretIds, shareSecretId)
+	}
+
+	data.SecretId = types.StringValue(secretId)
+	data.Key = types.StringValue(kh.String())
+	list, diags := types.ListValueFrom(ctx, types.StringType, encrypted)
+	if diags.HasError() {
+		return fmt.Errorf("failed to store shares")
+	}
+	data.Shares = list
+	list, diags = types.ListValueFrom(ctx, types.StringType, shareSecretIds)
+	if diags.HasError() {
+		return fmt.Errorf("failed to store share secret ids")
+	}
+	data.ShareSecretIds = list
+	return nil
+}
+
+func (m *MEKSharesResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to compare when the resource is created or destroyed.
+	if req.Plan.Raw.IsNull() || req.State.Raw.IsNull() {
+		return
+	}
+
+	var state MEKSharesResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	kh, err := readMEKHandle(ctx, m.client, state.ProjectId.ValueString(), state.SecretId.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read MEK", err.Error())
+		return
+	}
+	if kh.String() == state.Key.ValueString() {
+		return
+	}
+	// The MEK was rotated or replaced, so the shares are stale. Replacing the
+	// resource deletes the old share secrets before splitting the new MEK.
+	tflog.Info(ctx, "MEK changed since it was split, replacing shares")
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("key"), types.StringUnknown())...)
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("shares"), types.ListUnknown(types.StringType))...)
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("share_secret_ids"), types.ListUnknown(types.StringType))...)
+	resp.RequiresReplace = append(resp.RequiresReplace, path.Root("key"))
+}
+
+func (m *MEKSharesResource) deleteShareSecret(ctx context.Context, projectId, secretId string) error {
+	delReq := &secretmanagerpb.DeleteSecretRequest{
+		Name: getSecretResourceName(projectId, secretId),
+	}
+	return m.client.DeleteSecret(ctx, delReq)
+}
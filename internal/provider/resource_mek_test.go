@@ -0,0 +1,133 @@
+package provider
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+
+	"github.com/google/tink/go/aead"
+	"github.com/google/tink/go/insecurecleartextkeyset"
+	"github.com/google/tink/go/keyset"
+)
+
+func TestParseMEKKeyset(t *testing.T) {
+	kh, err := keyset.NewHandle(aead.AES256GCMKeyTemplate())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var jsonKeyset, binaryKeyset bytes.Buffer
+	if err := insecurecleartextkeyset.Write(kh, keyset.NewJSONWriter(&jsonKeyset)); err != nil {
+		t.Fatal(err)
+	}
+	if err := insecurecleartextkeyset.Write(kh, keyset.NewBinaryWriter(&binaryKeyset)); err != nil {
+		t.Fatal(err)
+	}
+	original, err := aead.New(kh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := original.Encrypt([]byte("data key"), []byte("context"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, source := range map[string][]byte{
+		"json":          jsonKeyset.Bytes(),
+		"json newline":  append(jsonKeyset.Bytes(), '\n'),
+		"binary":        binaryKeyset.Bytes(),
+		"base64 binary": []byte(base64.StdEncoding.EncodeToString(binaryKeyset.Bytes()) + "\n"),
+	} {
+		parsed, err := parseMEK(source)
+		if err != nil {
+			t.Errorf("%s: parseMEK: %v", name, err)
+			continue
+		}
+		if parsed.KeysetInfo().PrimaryKeyId != kh.KeysetInfo().PrimaryKeyId {
+			t.Errorf("%s: primary key id = %d, want %d", name, parsed.KeysetInfo().PrimaryKeyId, kh.KeysetInfo().PrimaryKeyId)
+		}
+		testMEKDecrypts(t, name, parsed, ciphertext)
+	}
+}
+
+func TestParseMEKRawKey(t *testing.T) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	// Raw keys must not be trimmed
+	key[0], key[31] = ' ', '\n'
+
+	// Ciphertexts of the raw key are nonce, ciphertext and tag without a Tink
+	// key id prefix.
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := gcm.Seal(nonce, nonce, []byte("data key"), []byte("context"))
+
+	for name, source := range map[string][]byte{
+		"raw":            key,
+		"base64":         []byte(base64.StdEncoding.EncodeToString(key)),
+		"base64 newline": []byte(base64.StdEncoding.EncodeToString(key) + "\n"),
+	} {
+		parsed, err := parseMEK(source)
+		if err != nil {
+			t.Errorf("%s: parseMEK: %v", name, err)
+			continue
+		}
+		testMEKDecrypts(t, name, parsed, ciphertext)
+
+		// Data encrypted after the import can still be decrypted with the raw key.
+		primitive, err := aead.New(parsed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encrypted, err := primitive.Encrypt([]byte("new data"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := gcm.NonceSize()
+		if plaintext, err := gcm.Open(nil, encrypted[:n], encrypted[n:], nil); err != nil || string(plaintext) != "new data" {
+			t.Errorf("%s: raw key decrypt = %q, %v", name, plaintext, err)
+		}
+	}
+}
+
+func TestParseMEKRejectsInvalidKeys(t *testing.T) {
+	for name, source := range map[string][]byte{
+		"short raw key":  make([]byte, 16),
+		"invalid base64": []byte("not a key"),
+		"invalid json":   []byte(`{"primaryKeyId": 1}`),
+	} {
+		if _, err := parseMEK(source); err == nil {
+			t.Errorf("%s: parseMEK accepted an invalid key", name)
+		}
+	}
+}
+
+func testMEKDecrypts(t *testing.T, name string, kh *keyset.Handle, ciphertext []byte) {
+	t.Helper()
+	primitive, err := aead.New(kh)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	plaintext, err := primitive.Decrypt(ciphertext, []byte("context"))
+	if err != nil {
+		t.Errorf("%s: decrypt: %v", name, err)
+		return
+	}
+	if string(plaintext) != "data key" {
+		t.Errorf("%s: decrypted %q, want %q", name, plaintext, "data key")
+	}
+}
@@ -3,28 +3,81 @@ package provider
 import (
 	"context"
 	"crypto/rand"
-	"crypto/sha512"
+	_ "embed"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"math/big"
+	"strings"
+	"time"
 
 	secretmanager "cloud.google.com/go/secretmanager/apiv1"
 	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int32validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.Resource = &RandomStringResource{}
 var _ resource.ResourceWithImportState = &RandomStringResource{}
+var _ resource.ResourceWithValidateConfig = &RandomStringResource{}
+var _ resource.ResourceWithModifyPlan = &RandomStringResource{}
+var _ resource.ResourceWithConfigValidators = &RandomStringResource{}
 
 type randomStringType string
 
 const (
 	password        = randomStringType("password")
 	registrationKey = randomStringType("registration_key")
+	uuidString      = randomStringType("uuid")
+	hexString       = randomStringType("hex")
+	base64Key       = randomStringType("base64_key")
+	passphrase      = randomStringType("passphrase")
+)
+
+type registrationKeyFormat string
+
+const (
+	registrationKeyFormatPlain   = registrationKeyFormat("plain")
+	registrationKeyFormatGrouped = registrationKeyFormat("grouped")
+
+	// registrationKeyAlphabet leaves out 0, 1, I and O, which are confused when
+	// keys are read aloud or written down.
+	registrationKeyAlphabet  = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+	registrationKeyGroupSize = 4
+)
+
+// Defaults of the types with an optional length.
+const (
+	defaultHexLength        = 32
+	defaultBase64KeyLength  = 32
+	defaultPassphraseLength = 6
+	defaultSeparator        = "-"
+)
+
+// wordList is the EFF short word list 2.0, https://www.eff.org/dice
+//
+//go:embed eff_short_wordlist.txt
+var wordList string
+
+const (
+	upperChars   = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	lowerChars   = "abcdefghijklmnopqrstuvwxyz"
+	numericChars = "0123456789"
+	// defaultSpecialChars leave out quotes, backslashes, '@' and '/', which break
+	// naive quoting in shells, SQL and config files. Most of them still have to be
+	// percent-encoded in connection URIs.
+	defaultSpecialChars = "!#$%&*()-_=+[]{}<>:?"
+	ambiguousChars      = "0O1lI|"
 )
 
 func NewRandomStringResource() resource.Resource {
@@ -38,13 +91,38 @@ type RandomStringResource struct {
 
 // RandomStringResourceModel describes the resource data model.
 type RandomStringResourceModel struct {
-	ProjectId types.String `tfsdk:"project_id"`
-	Namespace types.String `tfsdk:"namespace"`
-	Suffix    types.String `tfsdk:"suffix"`
-	Type      types.String `tfsdk:"type"`
-	Length    types.Int32  `tfsdk:"length"`
-	SecretId  types.String `tfsdk:"secret_id"`
-	Value     types.String `tfsdk:"value"`
+	ProjectId        types.String `tfsdk:"project_id"`
+	Namespace        types.String `tfsdk:"namespace"`
+	Suffix           types.String `tfsdk:"suffix"`
+	Type             types.String `tfsdk:"type"`
+	Length           types.Int32  `tfsdk:"length"`
+	Separator        types.String `tfsdk:"separator"`
+	Format           types.String `tfsdk:"format"`
+	Special          types.Bool   `tfsdk:"special"`
+	OverrideSpecial  types.String `tfsdk:"override_special"`
+	MinUpper         types.Int32  `tfsdk:"min_upper"`
+	MinLower         types.Int32  `tfsdk:"min_lower"`
+	MinNumeric       types.Int32  `tfsdk:"min_numeric"`
+	MinSpecial       types.Int32  `tfsdk:"min_special"`
+	ExcludeAmbiguous types.Bool   `tfsdk:"exclude_ambiguous"`
+	HashAlgorithm    types.String `tfsdk:"hash_algorithm"`
+	RotationDays     types.Int32  `tfsdk:"rotation_days"`
+	LastRotated      types.String `tfsdk:"last_rotated"`
+	Keepers          types.Map    `tfsdk:"keepers"`
+	Labels           types.Map    `tfsdk:"labels"`
+	SecretId         types.String `tfsdk:"secret_id"`
+	Value            types.String `tfsdk:"value"`
+}
+
+// randomStringOptions describes the characters of a generated string.
+type randomStringOptions struct {
+	special          bool
+	overrideSpecial  string
+	minUpper         int
+	minLower         int
+	minNumeric       int
+	minSpecial       int
+	excludeAmbiguous bool
 }
 
 func (r *RandomStringResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -54,7 +132,8 @@ func (r *RandomStringResource) Metadata(ctx context.Context, req resource.Metada
 func (r *RandomStringResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
 		// This description is used by the documentation generator and the language server.
-		MarkdownDescription: "Random string resource to create a random password and/or ClearBlade registration key",
+		MarkdownDescription: "Random string stored in GCP Secrets. Generates passwords, ClearBlade registration keys, UUIDs, hex and base64 " +
+			"keys and passphrases",
 
 		Attributes: map[string]schema.Attribute{
 			"project_id": schema.StringAttribute{
@@ -70,18 +149,103 @@ func (r *RandomStringResource) Schema(ctx context.Context, req resource.SchemaRe
 				Required:            true,
 			},
 			"type": schema.StringAttribute{
-				MarkdownDescription: "Random string type",
+				MarkdownDescription: "Random string type, one of `password`, `registration_key`, `uuid`, `hex`, `base64_key` and `passphrase`",
 				Required:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(string(password), string(registrationKey), string(uuidString), string(hexString), string(base64Key), string(passphrase)),
+				},
 			},
 			"length": schema.Int32Attribute{
-				MarkdownDescription: "Length of random string",
-				Required:            true,
+				MarkdownDescription: "Length of random string. Characters for `password` (6 to 30) and `registration_key` (6 to 10), " +
+					"bytes for `hex` (8 to 128, default 32) and `base64_key` (16 to 128, default 32), " +
+					"words for `passphrase` (4 to 20, default 6). Not used by `uuid`",
+				Optional:   true,
+				Validators: []validator.Int32{int32validator.AtLeast(1)},
+			},
+			"separator": schema.StringAttribute{
+				MarkdownDescription: "Separator of the words of a `passphrase`. Defaults to `" + defaultSeparator + "`",
+				Optional:            true,
+			},
+			"format": schema.StringAttribute{
+				MarkdownDescription: "Format of a `registration_key`. `plain` (default) uses the configured character sets, " +
+					"`grouped` uses upper case letters and digits without `0`, `1`, `I` and `O` in dash separated groups of four, " +
+					"with a trailing Luhn mod 32 check character. For `grouped`, `length` counts the characters including the check character " +
+					"but not the dashes, 7 to 11",
+				Optional: true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(string(registrationKeyFormatPlain), string(registrationKeyFormatGrouped)),
+				},
+			},
+			"special": schema.BoolAttribute{
+				MarkdownDescription: "Include special characters in a `password` or plain `registration_key`. Defaults to false",
+				Optional:            true,
+			},
+			"override_special": schema.StringAttribute{
+				MarkdownDescription: "Special characters to use instead of the default `" + defaultSpecialChars + "`",
+				Optional:            true,
+			},
+			"min_upper": schema.Int32Attribute{
+				MarkdownDescription: "Minimum number of upper case letters",
+				Optional:            true,
+				Validators:          []validator.Int32{int32validator.AtLeast(0)},
+			},
+			"min_lower": schema.Int32Attribute{
+				MarkdownDescription: "Minimum number of lower case letters",
+				Optional:            true,
+				Validators:          []validator.Int32{int32validator.AtLeast(0)},
+			},
+			"min_numeric": schema.Int32Attribute{
+				MarkdownDescription: "Minimum number of digits",
+				Optional:            true,
+				Validators:          []validator.Int32{int32validator.AtLeast(0)},
+			},
+			"min_special": schema.Int32Attribute{
+				MarkdownDescription: "Minimum number of special characters, requires `special`",
+				Optional:            true,
+				Validators:          []validator.Int32{int32validator.AtLeast(0)},
+			},
+			"exclude_ambiguous": schema.BoolAttribute{
+				MarkdownDescription: "Leave out characters which are easily confused, `" + ambiguousChars + "`",
+				Optional:            true,
+			},
+			"hash_algorithm": schema.StringAttribute{
+				MarkdownDescription: "Algorithm of the `value` of a password, one of `sha512`, `bcrypt`, `scrypt`, `argon2id`, `pbkdf2` and " +
+					"`scram-sha-256` (PostgreSQL verifier). Changing it does not generate a new password. Defaults to `sha512`. " +
+					"Every refresh verifies the stored hash, which costs 32 MiB of memory for `scrypt`, 64 MiB for `argon2id`, " +
+					"600000 iterations for `pbkdf2` and cost 10 for `bcrypt`, per resource",
+				Optional:   true,
+				Validators: []validator.String{stringvalidator.OneOf(hashAlgorithms...)},
+			},
+			"rotation_days": schema.Int32Attribute{
+				MarkdownDescription: "Generate a new random string when the current one is older than this many days",
+				Optional:            true,
+				Validators:          []validator.Int32{int32validator.AtLeast(1)},
+			},
+			"last_rotated": schema.StringAttribute{
+				MarkdownDescription: "When the random string was last generated, in RFC 3339 format",
+				Computed:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"keepers": schema.MapAttribute{
+				ElementType:         types.StringType,
+				MarkdownDescription: "Arbitrary values which generate a new random string when changed",
+				Optional:            true,
+			},
+			"labels": schema.MapAttribute{
+				ElementType:         types.StringType,
+				MarkdownDescription: "Labels of the secret. Changing them does not generate a new random string",
+				Optional:            true,
 			},
 			"secret_id": schema.StringAttribute{
-				Computed: true,
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
 			},
 			"value": schema.StringAttribute{
-				Computed: true,
+				MarkdownDescription: "Hash of the password for `password`, the generated string for all other types. " +
+					"Sensitive, as `hex`, `base64_key` and `passphrase` values are keys and credentials",
+				Computed:      true,
+				Sensitive:     true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
 			},
 		},
 	}
@@ -93,18 +257,18 @@ func (r *RandomStringResource) Configure(ctx context.Context, req resource.Confi
 		return
 	}
 
-	client, ok := req.ProviderData.(*secretmanager.Client)
+	providerData, ok := req.ProviderData.(*ClearBladeGoogleProviderData)
 
 	if !ok {
 		resp.Diagnostics.AddError(
 			"Unexpected Resource Configure Type",
-			fmt.Sprintf("Expected *secretmanager.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected *ClearBladeGoogleProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 
 		return
 	}
 
-	r.client = client
+	r.client = providerData.client
 }
 
 func (r *RandomStringResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
@@ -116,6 +280,11 @@ func (r *RandomStringResource) Create(ctx context.Context, req resource.CreateRe
 		return
 	}
 
+	// Validators skip unknown values, so check the length again at apply time
+	if err := data.checkLength(); err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("length"), "Invalid length attribute", err.Error())
+		return
+	}
 	switch randomStringType(data.Type.ValueString()) {
 	case password:
 		if err := r.createPassword(ctx, &data); err != nil {
@@ -127,9 +296,16 @@ func (r *RandomStringResource) Create(ctx context.Context, req resource.CreateRe
 			resp.Diagnostics.AddError("Failed to create registration key", err.Error())
 			return
 		}
+	case uuidString, hexString, base64Key, passphrase:
+		if err := r.createRandomValue(ctx, &data); err != nil {
+			resp.Diagnostics.AddError("Failed to create "+data.Type.ValueString(), err.Error())
+			return
+		}
 	default:
 		resp.Diagnostics.AddError("Invalid type attribute", data.Type.ValueString())
+		return
 	}
+	data.LastRotated = types.StringValue(time.Now().UTC().Format(time.RFC3339))
 
 	// Save data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
@@ -145,21 +321,41 @@ func (r *RandomStringResource) Read(ctx context.Context, req resource.ReadReques
 	}
 
 	secretId := getSecretId(data.Namespace.ValueString(), data.Suffix.ValueString())
-	resource := getSecretResourceName(data.ProjectId.ValueString(), secretId) + "/versions/latest"
-	secReq := &secretmanagerpb.AccessSecretVersionRequest{
-		Name: resource,
-	}
-	rval, err := r.client.AccessSecretVersion(ctx, secReq)
+	payload, err := getLatestSecretVersion(ctx, r.client, data.ProjectId.ValueString(), secretId)
 	if err != nil {
-		resp.Diagnostics.AddError("Faled to get MEK secret", err.Error())
+		if isSecretNotFound(err) {
+			tflog.Warn(ctx, "Random string secret not found, removing from state")
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.AddError("Failed to get random string secret", err.Error())
 		return
 	}
 	data.SecretId = types.StringValue(secretId)
+	if data.LastRotated.IsNull() {
+		// Created before rotation was tracked, the latest version was added when
+		// the value was last generated.
+		createTime, err := getLatestSecretVersionCreateTime(ctx, r.client, data.ProjectId.ValueString(), secretId)
+		if err != nil {
+			resp.Diagnostics.AddError("Failed to get secret version", err.Error())
+			return
+		}
+		data.LastRotated = types.StringValue(createTime.UTC().Format(time.RFC3339))
+	}
 	switch randomStringType(data.Type.ValueString()) {
 	case password:
-		data.Value = types.StringValue(hashPassword(string(rval.Payload.Data)))
+		// Salted hashes differ every time, so only hash again when the password
+		// no longer matches.
+		if !verifyPasswordHash(data.hashAlgorithm(), string(payload), data.Value.ValueString()) {
+			hash, err := hashPasswordWith(data.hashAlgorithm(), string(payload))
+			if err != nil {
+				resp.Diagnostics.AddError("Failed to hash password", err.Error())
+				return
+			}
+			data.Value = types.StringValue(hash)
+		}
 	default:
-		data.Value = types.StringValue(string(rval.Payload.Data))
+		data.Value = types.StringValue(string(payload))
 	}
 
 	// Save updated data into Terraform state
@@ -167,27 +363,70 @@ func (r *RandomStringResource) Read(ctx context.Context, req resource.ReadReques
 }
 
 func (r *RandomStringResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
-	var data RandomStringResourceModel
+	var data, state RandomStringResourceModel
 
-	// Read Terraform plan data into the model
+	// Read Terraform plan and prior state data into the models
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	switch randomStringType(data.Type.ValueString()) {
-	case password:
-		if err := r.updatePassword(ctx, &data); err != nil {
-			resp.Diagnostics.AddError("Failed to update password", err.Error())
+	// ModifyPlan leaves last_rotated unknown when a new random string is needed,
+	// and only the value unknown when the password has to be hashed again. Other
+	// changes keep the current value.
+	if data.Value.IsUnknown() && !data.LastRotated.IsUnknown() {
+		if err := r.rehashPassword(ctx, &data); err != nil {
+			resp.Diagnostics.AddError("Failed to hash password", err.Error())
 			return
 		}
-	case registrationKey:
-		if err := r.updateRegistrationKey(ctx, &data); err != nil {
-			resp.Diagnostics.AddError("Failed to update registration key", err.Error())
+	} else if data.LastRotated.IsUnknown() {
+		if err := data.checkLength(); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("length"), "Invalid length attribute", err.Error())
+			return
+		}
+		switch randomStringType(data.Type.ValueString()) {
+		case password:
+			if err := r.updatePassword(ctx, &data); err != nil {
+				resp.Diagnostics.AddError("Failed to update password", err.Error())
+				return
+			}
+		case registrationKey:
+			if err := r.updateRegistrationKey(ctx, &data); err != nil {
+				resp.Diagnostics.AddError("Failed to update registration key", err.Error())
+				return
+			}
+		case uuidString, hexString, base64Key, passphrase:
+			if err := r.updateRandomValue(ctx, &data); err != nil {
+				resp.Diagnostics.AddError("Failed to update "+data.Type.ValueString(), err.Error())
+				return
+			}
+		default:
+			resp.Diagnostics.AddError("Invalid type attribute", data.Type.ValueString())
+			return
+		}
+		data.LastRotated = types.StringValue(time.Now().UTC().Format(time.RFC3339))
+	}
+
+	// The random string was regenerated in the new secret, so the old one is no
+	// longer managed.
+	if data.moved(&state) {
+		if err := deleteSecret(ctx, r.client, state.ProjectId.ValueString(), state.SecretId.ValueString()); err != nil {
+			resp.Diagnostics.AddError("Failed to delete previous secret", err.Error())
+			return
+		}
+	}
+
+	if !data.Labels.Equal(state.Labels) {
+		labels, err := secretLabels(ctx, data.Labels)
+		if err != nil {
+			resp.Diagnostics.AddError("Invalid labels attribute", err.Error())
+			return
+		}
+		if err := updateSecretLabels(ctx, r.client, data.ProjectId.ValueString(), data.SecretId.ValueString(), labels); err != nil {
+			resp.Diagnostics.AddError("Failed to update secret labels", err.Error())
 			return
 		}
-	default:
-		resp.Diagnostics.AddError("Invalid type attribute", data.Type.ValueString())
 	}
 
 	// Save updated data into Terraform state
@@ -214,56 +453,179 @@ func (r *RandomStringResource) Delete(ctx context.Context, req resource.DeleteRe
 	}
 }
 
+func (r *RandomStringResource) ConfigValidators(ctx context.Context) []resource.ConfigValidator {
+	return []resource.ConfigValidator{randomStringLengthValidator{}}
+}
+
+func (r *RandomStringResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data RandomStringResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	minimums := map[string]types.Int32{
+		"min_upper":   data.MinUpper,
+		"min_lower":   data.MinLower,
+		"min_numeric": data.MinNumeric,
+		"min_special": data.MinSpecial,
+	}
+	total := int32(0)
+	known := !data.Length.IsUnknown()
+	for _, value := range minimums {
+		if value.IsUnknown() {
+			known = false
+			continue
+		}
+		total += value.ValueInt32()
+	}
+	if known && !data.Length.IsNull() && total > data.Length.ValueInt32() {
+		resp.Diagnostics.AddAttributeError(path.Root("length"), "Invalid length attribute",
+			fmt.Sprintf("the minimum character counts add up to %d, which is more than length %d", total, data.Length.ValueInt32()))
+	}
+	if data.MinSpecial.ValueInt32() > 0 && !data.Special.IsUnknown() && !data.Special.ValueBool() {
+		resp.Diagnostics.AddAttributeError(path.Root("min_special"), "Invalid min_special attribute", "min_special requires special to be true")
+	}
+	if !data.Type.IsUnknown() {
+		t := randomStringType(data.Type.ValueString())
+		characterSet := data.characterSetAttributes()
+		if !data.Format.IsNull() && !data.Format.IsUnknown() {
+			switch format := registrationKeyFormat(data.Format.ValueString()); {
+			case t != registrationKey:
+				resp.Diagnostics.AddAttributeError(path.Root("format"), "Invalid format attribute", "format only applies to the registration_key type")
+			case format == registrationKeyFormatGrouped && len(characterSet) > 0:
+				resp.Diagnostics.AddAttributeError(path.Root("format"), "Invalid format attribute",
+					"the grouped format uses a fixed alphabet, so the character set attributes cannot be set")
+			}
+		}
+		if t != password && t != registrationKey {
+			for _, name := range characterSet {
+				resp.Diagnostics.AddAttributeError(path.Root(name), "Invalid "+name+" attribute",
+					fmt.Sprintf("%s only applies to the password and registration_key types, %s does not use character sets", name, t))
+			}
+		}
+		if !data.Separator.IsNull() && t != passphrase {
+			resp.Diagnostics.AddAttributeError(path.Root("separator"), "Invalid separator attribute", "separator only applies to the passphrase type")
+		}
+	}
+	if !data.HashAlgorithm.IsNull() && !data.Type.IsUnknown() && randomStringType(data.Type.ValueString()) != password {
+		resp.Diagnostics.AddAttributeError(path.Root("hash_algorithm"), "Invalid hash_algorithm attribute",
+			"hash_algorithm only applies to the password type")
+	}
+	if !data.OverrideSpecial.IsNull() && !data.OverrideSpecial.IsUnknown() && !data.Special.IsUnknown() {
+		if !data.Special.ValueBool() {
+			resp.Diagnostics.AddAttributeError(path.Root("override_special"), "Invalid override_special attribute",
+				"override_special requires special to be true")
+		} else if len(data.options().specialChars()) == 0 {
+			resp.Diagnostics.AddAttributeError(path.Root("override_special"), "Invalid override_special attribute",
+				"override_special must contain at least one ASCII character which is not excluded")
+		}
+	}
+}
+
+func (r *RandomStringResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to regenerate when the resource is created or destroyed.
+	if req.Plan.Raw.IsNull() || req.State.Raw.IsNull() {
+		return
+	}
+
+	var plan, state RandomStringResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	regenerate := plan.regenerates(&state)
+	if days := plan.RotationDays.ValueInt32(); days > 0 && !regenerate {
+		// An unparsable timestamp rotates as well
+		lastRotated, _ := time.Parse(time.RFC3339, state.LastRotated.ValueString())
+		if due := lastRotated.AddDate(0, 0, int(days)); !time.Now().Before(due) {
+			resp.Diagnostics.AddWarning("Random string will be rotated",
+				fmt.Sprintf("%s was last rotated on %s, more than %d days ago", state.SecretId.ValueString(), state.LastRotated.ValueString(), days))
+			regenerate = true
+		}
+	}
+	rehash := randomStringType(plan.Type.ValueString()) == password && plan.hashAlgorithm() != state.hashAlgorithm()
+	if !regenerate && !rehash {
+		return
+	}
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("value"), types.StringUnknown())...)
+	if regenerate {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("last_rotated"), types.StringUnknown())...)
+	}
+	if plan.moved(&state) {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("secret_id"), types.StringUnknown())...)
+	}
+}
+
 func (r *RandomStringResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
 	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
 }
 
 func (r *RandomStringResource) createPassword(ctx context.Context, data *RandomStringResourceModel) error {
-	if err := validatePasswordLength(data.Length.ValueInt32()); err != nil {
-		return fmt.Errorf("Invalid password length: %w", err)
-	}
 	secretId := getSecretId(data.Namespace.ValueString(), data.Suffix.ValueString())
-	if err := createSecret(ctx, r.client, data.ProjectId.ValueString(), secretId); err != nil {
-		return fmt.Errorf("Failed to create secret: %w", err)
+	if err := r.createSecret(ctx, data, secretId); err != nil {
+		return err
 	}
-	password, err := generateRandomString(int(data.Length.ValueInt32()))
+	password, err := generateRandomString(int(data.Length.ValueInt32()), data.options())
 	if err != nil {
 		return fmt.Errorf("Failed to generate random password: %w", err)
 	}
 	if err := addSecretVersion(ctx, r.client, data.ProjectId.ValueString(), secretId, []byte(password)); err != nil {
 		return fmt.Errorf("Failed to add password to secret: %w", err)
 	}
-	data.Value = types.StringValue(hashPassword(password))
+	hash, err := hashPasswordWith(data.hashAlgorithm(), password)
+	if err != nil {
+		return fmt.Errorf("Failed to hash password: %w", err)
+	}
+	data.Value = types.StringValue(hash)
 	data.SecretId = types.StringValue(secretId)
 	return nil
 }
 
 func (r *RandomStringResource) updatePassword(ctx context.Context, data *RandomStringResourceModel) error {
-	if err := validatePasswordLength(data.Length.ValueInt32()); err != nil {
-		return fmt.Errorf("Invalid password length: %w", err)
-	}
 	secretId := getSecretId(data.Namespace.ValueString(), data.Suffix.ValueString())
+	// The secret only has to be created when namespace or suffix changed
+	if err := r.createSecret(ctx, data, secretId); err != nil {
+		return err
+	}
 	data.SecretId = types.StringValue(secretId)
-	password, err := generateRandomString(int(data.Length.ValueInt32()))
+	password, err := generateRandomString(int(data.Length.ValueInt32()), data.options())
 	if err != nil {
 		return fmt.Errorf("Failed to generate random password: %w", err)
 	}
 	if err := addSecretVersion(ctx, r.client, data.ProjectId.ValueString(), secretId, []byte(password)); err != nil {
 		return fmt.Errorf("Failed to add password to secret: %w", err)
 	}
-	data.Value = types.StringValue(hashPassword(password))
+	hash, err := hashPasswordWith(data.hashAlgorithm(), password)
+	if err != nil {
+		return fmt.Errorf("Failed to hash password: %w", err)
+	}
+	data.Value = types.StringValue(hash)
 	return nil
 }
 
-func (r *RandomStringResource) createRegistrationKey(ctx context.Context, data *RandomStringResourceModel) error {
-	if err := validateRegistrationKeyLength(data.Length.ValueInt32()); err != nil {
-		return fmt.Errorf("Invalid registration key length: %w", err)
+func (r *RandomStringResource) rehashPassword(ctx context.Context, data *RandomStringResourceModel) error {
+	password, err := getLatestSecretVersion(ctx, r.client, data.ProjectId.ValueString(), data.SecretId.ValueString())
+	if err != nil {
+		return fmt.Errorf("Failed to get password secret: %w", err)
+	}
+	hash, err := hashPasswordWith(data.hashAlgorithm(), string(password))
+	if err != nil {
+		return err
 	}
+	data.Value = types.StringValue(hash)
+	return nil
+}
+
+func (r *RandomStringResource) createRegistrationKey(ctx context.Context, data *RandomStringResourceModel) error {
 	secretId := getSecretId(data.Namespace.ValueString(), data.Suffix.ValueString())
-	if err := createSecret(ctx, r.client, data.ProjectId.ValueString(), secretId); err != nil {
-		return fmt.Errorf("Failed to create secret: %w", err)
+	if err := r.createSecret(ctx, data, secretId); err != nil {
+		return err
 	}
-	registrationKey, err := generateRandomString(int(data.Length.ValueInt32()))
+	registrationKey, err := generateRegistrationKey(int(data.Length.ValueInt32()), data.registrationKeyFormat(), data.options())
 	if err != nil {
 		return fmt.Errorf("Failed to generate random registration key: %w", err)
 	}
@@ -276,12 +638,13 @@ func (r *RandomStringResource) createRegistrationKey(ctx context.Context, data *
 }
 
 func (r *RandomStringResource) updateRegistrationKey(ctx context.Context, data *RandomStringResourceModel) error {
-	if err := validateRegistrationKeyLength(data.Length.ValueInt32()); err != nil {
-		return fmt.Errorf("Invalid registration key length: %w", err)
-	}
 	secretId := getSecretId(data.Namespace.ValueString(), data.Suffix.ValueString())
+	// The secret only has to be created when namespace or suffix changed
+	if err := r.createSecret(ctx, data, secretId); err != nil {
+		return err
+	}
 	data.SecretId = types.StringValue(secretId)
-	registrationKey, err := generateRandomString(int(data.Length.ValueInt32()))
+	registrationKey, err := generateRegistrationKey(int(data.Length.ValueInt32()), data.registrationKeyFormat(), data.options())
 	if err != nil {
 		return fmt.Errorf("Failed to generate random registration key: %w", err)
 	}
@@ -292,45 +655,367 @@ func (r *RandomStringResource) updateRegistrationKey(ctx context.Context, data *
 	return nil
 }
 
+func (r *RandomStringResource) createRandomValue(ctx context.Context, data *RandomStringResourceModel) error {
+	// The secret is created by updateRandomValue unless it exists
+	return r.updateRandomValue(ctx, data)
+}
+
+func (r *RandomStringResource) updateRandomValue(ctx context.Context, data *RandomStringResourceModel) error {
+	secretId := getSecretId(data.Namespace.ValueString(), data.Suffix.ValueString())
+	if err := r.createSecret(ctx, data, secretId); err != nil {
+		return err
+	}
+	data.SecretId = types.StringValue(secretId)
+	value, err := generateRandomValue(randomStringType(data.Type.ValueString()), data.length(), data.Separator)
+	if err != nil {
+		return err
+	}
+	if err := addSecretVersion(ctx, r.client, data.ProjectId.ValueString(), secretId, []byte(value)); err != nil {
+		return fmt.Errorf("Failed to add %s to secret: %w", data.Type.ValueString(), err)
+	}
+	data.Value = types.StringValue(value)
+	return nil
+}
+
+func (r *RandomStringResource) createSecret(ctx context.Context, data *RandomStringResourceModel, secretId string) error {
+	labels, err := secretLabels(ctx, data.Labels)
+	if err != nil {
+		return fmt.Errorf("Invalid labels: %w", err)
+	}
+	if err := createSecretWithLabels(ctx, r.client, data.ProjectId.ValueString(), secretId, labels); err != nil {
+		return fmt.Errorf("Failed to create secret: %w", err)
+	}
+	return nil
+}
+
+// characterSetAttributes returns the names of the set attributes which select
+// the characters of passwords and plain registration keys.
+func (d *RandomStringResourceModel) characterSetAttributes() []string {
+	var names []string
+	for _, attribute := range []struct {
+		name  string
+		value attr.Value
+	}{
+		{"special", d.Special},
+		{"override_special", d.OverrideSpecial},
+		{"min_upper", d.MinUpper},
+		{"min_lower", d.MinLower},
+		{"min_numeric", d.MinNumeric},
+		{"min_special", d.MinSpecial},
+		{"exclude_ambiguous", d.ExcludeAmbiguous},
+	} {
+		if !attribute.value.IsNull() {
+			names = append(names, attribute.name)
+		}
+	}
+	return names
+}
+
+// moved reports whether the secret is different from the one of state.
+func (d *RandomStringResourceModel) moved(state *RandomStringResourceModel) bool {
+	return !d.ProjectId.Equal(state.ProjectId) || !d.Namespace.Equal(state.Namespace) || !d.Suffix.Equal(state.Suffix)
+}
+
+// regenerates reports whether a new random string is needed to go from state.
+func (d *RandomStringResourceModel) regenerates(state *RandomStringResourceModel) bool {
+	return d.moved(state) || !d.Type.Equal(state.Type) || !d.Length.Equal(state.Length) ||
+		!d.Separator.Equal(state.Separator) || !d.Format.Equal(state.Format) || !d.Special.Equal(state.Special) || !d.OverrideSpecial.Equal(state.OverrideSpecial) ||
+		!d.MinUpper.Equal(state.MinUpper) || !d.MinLower.Equal(state.MinLower) ||
+		!d.MinNumeric.Equal(state.MinNumeric) || !d.MinSpecial.Equal(state.MinSpecial) ||
+		!d.ExcludeAmbiguous.Equal(state.ExcludeAmbiguous) || !d.Keepers.Equal(state.Keepers)
+}
+
+// length returns the configured length or the default of the type.
+func (d *RandomStringResourceModel) length() int {
+	if d.Length.IsNull() {
+		bounds, _ := randomStringLengthBounds(randomStringType(d.Type.ValueString()), d.registrationKeyFormat())
+		return int(bounds.def)
+	}
+	return int(d.Length.ValueInt32())
+}
+
+// checkLength checks the length against the bounds of the type.
+func (d *RandomStringResourceModel) checkLength() error {
+	t := randomStringType(d.Type.ValueString())
+	bounds, ok := randomStringLengthBounds(t, d.registrationKeyFormat())
+	if !ok {
+		return nil
+	}
+	if d.Length.IsNull() && bounds.def == 0 {
+		return fmt.Errorf("length is required for the %s type", t)
+	}
+	if n := int32(d.length()); n < bounds.min || n > bounds.max {
+		return fmt.Errorf("%s length must be between %d and %d, got: %d", t, bounds.min, bounds.max, n)
+	}
+	return nil
+}
+
+func (d *RandomStringResourceModel) registrationKeyFormat() registrationKeyFormat {
+	if d.Format.IsNull() {
+		return registrationKeyFormatPlain
+	}
+	return registrationKeyFormat(d.Format.ValueString())
+}
+
+func (d *RandomStringResourceModel) hashAlgorithm() hashAlgorithm {
+	if d.HashAlgorithm.IsNull() {
+		return hashAlgorithmSHA512
+	}
+	return hashAlgorithm(d.HashAlgorithm.ValueString())
+}
+
+// randomStringLengthValidator checks length against the bounds of the type.
+type randomStringLengthValidator struct{}
+
+func (v randomStringLengthValidator) Description(ctx context.Context) string {
+	return "length must be within the bounds of the type"
+}
+
+func (v randomStringLengthValidator) MarkdownDescription(ctx context.Context) string {
+	return "`length` must be within the bounds of the `type`"
+}
+
+func (v randomStringLengthValidator) ValidateResource(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data RandomStringResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() || data.Type.IsUnknown() || data.Length.IsUnknown() || data.Format.IsUnknown() {
+		return
+	}
+
+	t := randomStringType(data.Type.ValueString())
+	bounds, ok := randomStringLengthBounds(t, data.registrationKeyFormat())
+	switch {
+	case !ok && t == uuidString && !data.Length.IsNull():
+		resp.Diagnostics.AddAttributeError(path.Root("length"), "Invalid length attribute", "length cannot be set for the uuid type")
+	case !ok:
+		// The type validator reports unknown types
+	case data.Length.IsNull() && bounds.def == 0:
+		resp.Diagnostics.AddAttributeError(path.Root("length"), "Missing length attribute", "length is required for the "+string(t)+" type")
+	case !data.Length.IsNull() && (data.Length.ValueInt32() < bounds.min || data.Length.ValueInt32() > bounds.max):
+		resp.Diagnostics.AddAttributeError(path.Root("length"), "Invalid length attribute",
+			fmt.Sprintf("%s length must be between %d and %d, got: %d", t, bounds.min, bounds.max, data.Length.ValueInt32()))
+	}
+}
+
+func (d *RandomStringResourceModel) options() randomStringOptions {
+	return randomStringOptions{
+		special:          d.Special.ValueBool(),
+		overrideSpecial:  d.OverrideSpecial.ValueString(),
+		minUpper:         int(d.MinUpper.ValueInt32()),
+		minLower:         int(d.MinLower.ValueInt32()),
+		minNumeric:       int(d.MinNumeric.ValueInt32()),
+		minSpecial:       int(d.MinSpecial.ValueInt32()),
+		excludeAmbiguous: d.ExcludeAmbiguous.ValueBool(),
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
-func generateRandomString(n int) (string, error) {
-	const letters = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
-	ret := make([]byte, n)
-	for i := 0; i < n; i++ {
-		num, err := rand.Int(rand.Reader, big.NewInt(int64(len(letters))))
+func (o randomStringOptions) chars(set string) string {
+	if !o.excludeAmbiguous {
+		return set
+	}
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(ambiguousChars, r) {
+			return -1
+		}
+		return r
+	}, set)
+}
+
+// specialChars returns the distinct printable ASCII special characters to use.
+func (o randomStringOptions) specialChars() string {
+	if !o.special {
+		return ""
+	}
+	set := defaultSpecialChars
+	if o.overrideSpecial != "" {
+		set = o.overrideSpecial
+	}
+	var chars strings.Builder
+	for _, r := range o.chars(set) {
+		if r > ' ' && r < 0x7f && !strings.ContainsRune(chars.String(), r) {
+			chars.WriteRune(r)
+		}
+	}
+	return chars.String()
+}
+
+// generateRandomString returns a random string of n characters. The minimum
+// number of characters of each class are drawn first, the rest from all enabled
+// classes, and the result is shuffled. All draws are uniform.
+func generateRandomString(n int, opts randomStringOptions) (string, error) {
+	upper, lower, numeric := opts.chars(upperChars), opts.chars(lowerChars), opts.chars(numericChars)
+	special := opts.specialChars()
+	if n < 1 {
+		return "", fmt.Errorf("length must be positive, got: %d", n)
+	}
+	if opts.minUpper+opts.minLower+opts.minNumeric+opts.minSpecial > n {
+		return "", fmt.Errorf("minimum character counts exceed length %d", n)
+	}
+	if opts.minSpecial > 0 && special == "" {
+		return "", fmt.Errorf("min_special requires special characters")
+	}
+
+	ret := make([]byte, 0, n)
+	for _, class := range []struct {
+		chars string
+		count int
+	}{
+		{upper, opts.minUpper},
+		{lower, opts.minLower},
+		{numeric, opts.minNumeric},
+		{special, opts.minSpecial},
+		{upper + lower + numeric + special, n - opts.minUpper - opts.minLower - opts.minNumeric - opts.minSpecial},
+	} {
+		for i := 0; i < class.count; i++ {
+			num, err := rand.Int(rand.Reader, big.NewInt(int64(len(class.chars))))
+			if err != nil {
+				return "", err
+			}
+			ret = append(ret, class.chars[num.Int64()])
+		}
+	}
+
+	// Fisher-Yates shuffle, so the required characters are not at the start
+	for i := len(ret) - 1; i > 0; i-- {
+		num, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
 		if err != nil {
 			return "", err
 		}
-		ret[i] = letters[num.Int64()]
+		j := num.Int64()
+		ret[i], ret[j] = ret[j], ret[i]
 	}
 
 	return string(ret), nil
 }
 
-func hashPassword(password string) string {
-	hasher := sha512.New()
-	hasher.Write([]byte(password))
-	sha := base64.URLEncoding.EncodeToString(hasher.Sum(nil))
-	return sha
+// generateRandomValue generates the value of the uuid, hex, base64_key and
+// passphrase types.
+func generateRandomValue(t randomStringType, n int, separator types.String) (string, error) {
+	if t != uuidString && n < 1 {
+		return "", fmt.Errorf("length must be positive, got: %d", n)
+	}
+	switch t {
+	case uuidString:
+		return generateUUID()
+	case hexString:
+		b, err := randomBytes(n)
+		return hex.EncodeToString(b), err
+	case base64Key:
+		b, err := randomBytes(n)
+		return base64.StdEncoding.EncodeToString(b), err
+	case passphrase:
+		sep := defaultSeparator
+		if !separator.IsNull() {
+			sep = separator.ValueString()
+		}
+		return generatePassphrase(n, sep)
+	}
+	return "", fmt.Errorf("Invalid type %s", t)
 }
 
-func validatePasswordLength(length int32) error {
-	if length < 6 {
-		return fmt.Errorf("Password length must be greater than or equal to 6")
-	} else if length > 30 {
-		return fmt.Errorf("Password length cannot be greater than 30")
-	} else {
-		return nil
+// lengthBounds are the allowed lengths of a random string type, and the
+// default length when it is optional.
+type lengthBounds struct {
+	min, max, def int32
+}
+
+// randomStringLengthBounds returns the length bounds of a type and registration
+// key format. The uuid type has no length.
+func randomStringLengthBounds(t randomStringType, format registrationKeyFormat) (lengthBounds, bool) {
+	switch t {
+	case password:
+		return lengthBounds{6, 30, 0}, true
+	case registrationKey:
+		if format == registrationKeyFormatGrouped {
+			// six random characters and the check character
+			return lengthBounds{7, 11, 0}, true
+		}
+		return lengthBounds{6, 10, 0}, true
+	case hexString:
+		return lengthBounds{8, 128, defaultHexLength}, true
+	case base64Key:
+		return lengthBounds{16, 128, defaultBase64KeyLength}, true
+	case passphrase:
+		return lengthBounds{4, 20, defaultPassphraseLength}, true
 	}
+	return lengthBounds{}, false
 }
 
-func validateRegistrationKeyLength(length int32) error {
-	if length < 6 {
-		return fmt.Errorf("Registration key length must be greater than or equal to 6")
-	} else if length > 10 {
-		return fmt.Errorf("Registration key length cannot be greater than 10")
-	} else {
-		return nil
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// generateUUID returns a random version 4 UUID.
+func generateUUID() (string, error) {
+	b, err := randomBytes(16)
+	if err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+func generatePassphrase(words int, separator string) (string, error) {
+	list := strings.Fields(wordList)
+	ret := make([]string, words)
+	for i := range ret {
+		num, err := rand.Int(rand.Reader, big.NewInt(int64(len(list))))
+		if err != nil {
+			return "", err
+		}
+		ret[i] = list[num.Int64()]
+	}
+	return strings.Join(ret, separator), nil
+}
+
+// generateRegistrationKey returns a registration key of length characters, not
+// counting the dashes of the grouped format.
+func generateRegistrationKey(length int, format registrationKeyFormat, opts randomStringOptions) (string, error) {
+	if format == registrationKeyFormatPlain {
+		return generateRandomString(length, opts)
+	}
+	if format != registrationKeyFormatGrouped {
+		return "", fmt.Errorf("Invalid registration key format %s", format)
+	}
+	if length < 2 {
+		return "", fmt.Errorf("grouped registration key length must be at least 2, got: %d", length)
+	}
+	key := make([]byte, length-1, length)
+	for i := range key {
+		num, err := rand.Int(rand.Reader, big.NewInt(int64(len(registrationKeyAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		key[i] = registrationKeyAlphabet[num.Int64()]
+	}
+	key = append(key, luhnModNCheckChar(string(key), registrationKeyAlphabet))
+
+	var groups []string
+	for len(key) > registrationKeyGroupSize {
+		groups = append(groups, string(key[:registrationKeyGroupSize]))
+		key = key[registrationKeyGroupSize:]
+	}
+	return strings.Join(append(groups, string(key)), "-"), nil
+}
+
+// luhnModNCheckChar returns the Luhn mod N check character of s, whose
+// characters are all from alphabet. Appending it detects any single mistyped
+// character and most transpositions of adjacent characters.
+func luhnModNCheckChar(s, alphabet string) byte {
+	n := len(alphabet)
+	factor, sum := 2, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		addend := factor * strings.IndexByte(alphabet, s[i])
+		sum += addend/n + addend%n
+		factor = 3 - factor
 	}
+	return alphabet[(n-sum%n)%n]
 }
@@ -0,0 +1,230 @@
+package provider
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestGenerateRejectsInvalidLength(t *testing.T) {
+	if _, err := generateRandomString(-1, randomStringOptions{}); err == nil {
+		t.Error("generateRandomString accepted a negative length")
+	}
+	if _, err := generateRegistrationKey(0, registrationKeyFormatGrouped, randomStringOptions{}); err == nil {
+		t.Error("generateRegistrationKey accepted a grouped length of 0")
+	}
+	if _, err := generateRandomValue(hexString, -1, types.StringNull()); err == nil {
+		t.Error("generateRandomValue accepted a negative length")
+	}
+}
+
+func TestLuhnModNCheckChar(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		want byte
+	}{
+		{"ABCDEF", '2'},
+		{"ZZZ", '5'},
+		{"7Q", 'G'},
+		{"2222", '2'},
+	} {
+		if got := luhnModNCheckChar(tc.s, registrationKeyAlphabet); got != tc.want {
+			t.Errorf("luhnModNCheckChar(%q) = %q, want %q", tc.s, got, tc.want)
+		}
+	}
+}
+
+func TestLuhnModNDetectsSubstitutions(t *testing.T) {
+	for _, payload := range []string{"ABCDEF", "23456789AB", "ZZZZZZ", "K7M2QX9"} {
+		key := payload + string(luhnModNCheckChar(payload, registrationKeyAlphabet))
+		for i := range key {
+			for j := 0; j < len(registrationKeyAlphabet); j++ {
+				c := registrationKeyAlphabet[j]
+				if c == key[i] {
+					continue
+				}
+				mistyped := key[:i] + string(c) + key[i+1:]
+				last := len(mistyped) - 1
+				if luhnModNCheckChar(mistyped[:last], registrationKeyAlphabet) == mistyped[last] {
+					t.Errorf("substituting %q at %d of %s gives the valid key %s", c, i, key, mistyped)
+				}
+			}
+		}
+	}
+}
+
+func TestGenerateGroupedRegistrationKey(t *testing.T) {
+	for length, pattern := range map[int]string{
+		7:  `^[^-]{4}-[^-]{3}$`,
+		8:  `^[^-]{4}-[^-]{4}$`,
+		9:  `^[^-]{4}-[^-]{4}-[^-]$`,
+		10: `^[^-]{4}-[^-]{4}-[^-]{2}$`,
+		11: `^[^-]{4}-[^-]{4}-[^-]{3}$`,
+	} {
+		key, err := generateRegistrationKey(length, registrationKeyFormatGrouped, randomStringOptions{})
+		if err != nil {
+			t.Fatalf("generateRegistrationKey(%d): %v", length, err)
+		}
+		if !regexp.MustCompile(pattern).MatchString(key) {
+			t.Errorf("generateRegistrationKey(%d) = %s, want groups matching %s", length, key, pattern)
+		}
+		chars := strings.ReplaceAll(key, "-", "")
+		if strings.Trim(chars, registrationKeyAlphabet) != "" {
+			t.Errorf("generateRegistrationKey(%d) = %s, has characters outside the alphabet", length, key)
+		}
+		last := len(chars) - 1
+		if luhnModNCheckChar(chars[:last], registrationKeyAlphabet) != chars[last] {
+			t.Errorf("generateRegistrationKey(%d) = %s, has an invalid check character", length, key)
+		}
+	}
+}
+
+func TestGenerateRandomString(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		length  int
+		opts    randomStringOptions
+		allowed string
+	}{
+		{
+			name:    "defaults",
+			length:  16,
+			allowed: upperChars + lowerChars + numericChars,
+		},
+		{
+			name:    "minimum counts",
+			length:  12,
+			opts:    randomStringOptions{special: true, minUpper: 3, minLower: 3, minNumeric: 3, minSpecial: 3},
+			allowed: upperChars + lowerChars + numericChars + defaultSpecialChars,
+		},
+		{
+			name:    "minimum counts fill the length",
+			length:  8,
+			opts:    randomStringOptions{minUpper: 4, minNumeric: 4},
+			allowed: upperChars + numericChars,
+		},
+		{
+			name:    "exclude ambiguous",
+			length:  30,
+			opts:    randomStringOptions{special: true, overrideSpecial: "|!", minNumeric: 10, excludeAmbiguous: true},
+			allowed: "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz23456789!",
+		},
+		{
+			name:    "override special",
+			length:  30,
+			opts:    randomStringOptions{special: true, overrideSpecial: "!!é ~~\t", minSpecial: 10},
+			allowed: upperChars + lowerChars + numericChars + "!~",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				s, err := generateRandomString(tc.length, tc.opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(s) != tc.length {
+					t.Fatalf("generateRandomString = %q, want %d characters", s, tc.length)
+				}
+				if strings.Trim(s, tc.allowed) != "" {
+					t.Fatalf("generateRandomString = %q, want only characters from %q", s, tc.allowed)
+				}
+				var upper, lower, numeric, special int
+				for _, r := range s {
+					switch {
+					case strings.ContainsRune(upperChars, r):
+						upper++
+					case strings.ContainsRune(lowerChars, r):
+						lower++
+					case strings.ContainsRune(numericChars, r):
+						numeric++
+					default:
+						special++
+					}
+				}
+				if upper < tc.opts.minUpper || lower < tc.opts.minLower || numeric < tc.opts.minNumeric || special < tc.opts.minSpecial {
+					t.Fatalf("generateRandomString = %q has %d upper, %d lower, %d numeric and %d special characters, want at least %d, %d, %d and %d",
+						s, upper, lower, numeric, special, tc.opts.minUpper, tc.opts.minLower, tc.opts.minNumeric, tc.opts.minSpecial)
+				}
+			}
+		})
+	}
+}
+
+func TestRandomStringSpecialChars(t *testing.T) {
+	for _, tc := range []struct {
+		opts randomStringOptions
+		want string
+	}{
+		{randomStringOptions{}, ""},
+		{randomStringOptions{special: true}, defaultSpecialChars},
+		{randomStringOptions{special: true, overrideSpecial: "!!é ~~\t"}, "!~"},
+		{randomStringOptions{special: true, overrideSpecial: "|!|", excludeAmbiguous: true}, "!"},
+	} {
+		if got := tc.opts.specialChars(); got != tc.want {
+			t.Errorf("specialChars(%+v) = %q, want %q", tc.opts, got, tc.want)
+		}
+	}
+}
+
+func TestRandomStringValidateConfigCharacterSets(t *testing.T) {
+	ctx := context.Background()
+	r := &RandomStringResource{}
+	schemaResp := &resource.SchemaResponse{}
+	r.Schema(ctx, resource.SchemaRequest{}, schemaResp)
+	s := schemaResp.Schema
+
+	for _, tc := range []struct {
+		typ  randomStringType
+		want []string
+	}{
+		{password, nil},
+		{registrationKey, nil},
+		{hexString, []string{"min_lower", "min_upper", "special"}},
+		{passphrase, []string{"min_lower", "min_upper", "special"}},
+	} {
+		t.Run(string(tc.typ), func(t *testing.T) {
+			data := RandomStringResourceModel{
+				ProjectId: types.StringValue("project"),
+				Namespace: types.StringValue("namespace"),
+				Suffix:    types.StringValue("-random"),
+				Type:      types.StringValue(string(tc.typ)),
+				Length:    types.Int32Value(10),
+				Special:   types.BoolValue(true),
+				MinUpper:  types.Int32Value(1),
+				MinLower:  types.Int32Value(1),
+				Keepers:   types.MapNull(types.StringType),
+				Labels:    types.MapNull(types.StringType),
+			}
+			config := tfsdk.Config{Schema: s, Raw: tftypes.NewValue(s.Type().TerraformType(ctx), nil)}
+			plan := tfsdk.Plan(config)
+			if diags := plan.Set(ctx, &data); diags.HasError() {
+				t.Fatal(diags)
+			}
+			config.Raw = plan.Raw
+
+			resp := &resource.ValidateConfigResponse{}
+			r.ValidateConfig(ctx, resource.ValidateConfigRequest{Config: config}, resp)
+			var got []string
+			for _, d := range resp.Diagnostics.Errors() {
+				withPath, ok := d.(diag.DiagnosticWithPath)
+				if !ok {
+					t.Fatalf("error %v has no path", d)
+				}
+				got = append(got, withPath.Path().String())
+			}
+			sort.Strings(got)
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("errors for %v, want %v: %v", got, tc.want, resp.Diagnostics)
+			}
+		})
+	}
+}
@@ -0,0 +1,344 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	"github.com/hashicorp/terraform-plugin-framework-validators/resourcevalidator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &SecretResource{}
+var _ resource.ResourceWithConfigValidators = &SecretResource{}
+var _ resource.ResourceWithModifyPlan = &SecretResource{}
+
+func NewSecretResource() resource.Resource {
+	return &SecretResource{}
+}
+
+// SecretResource defines the resource implementation.
+type SecretResource struct {
+	client *secretmanager.Client
+}
+
+// SecretResourceModel describes the resource data model.
+type SecretResourceModel struct {
+	ProjectId     types.String `tfsdk:"project_id"`
+	Namespace     types.String `tfsdk:"namespace"`
+	Suffix        types.String `tfsdk:"suffix"`
+	Payload       types.String `tfsdk:"payload"`
+	PayloadBase64 types.String `tfsdk:"payload_base64"`
+	PayloadFile   types.String `tfsdk:"payload_file"`
+	Labels        types.Map    `tfsdk:"labels"`
+	SecretId      types.String `tfsdk:"secret_id"`
+	PayloadSHA256 types.String `tfsdk:"payload_sha256"`
+	Version       types.String `tfsdk:"version"`
+}
+
+func (s *SecretResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_secret"
+}
+
+func (s *SecretResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Arbitrary secret stored in GCP Secrets as `<namespace><suffix>`, like license files, webhook tokens " +
+			"or SMTP credentials. A new secret version is added whenever the payload changes",
+
+		Attributes: map[string]schema.Attribute{
+			"project_id": schema.StringAttribute{
+				MarkdownDescription: "GCP project Id for storing the secret",
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"namespace": schema.StringAttribute{
+				MarkdownDescription: "Instance namespace",
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"suffix": schema.StringAttribute{
+				MarkdownDescription: "Secret Id suffix",
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"payload": schema.StringAttribute{
+				MarkdownDescription: "Payload as a string",
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"payload_base64": schema.StringAttribute{
+				MarkdownDescription: "Base64 encoded binary payload",
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"payload_file": schema.StringAttribute{
+				MarkdownDescription: "Path of a file holding the payload. Changes of the file contents are detected during plan",
+				Optional:            true,
+			},
+			"labels": schema.MapAttribute{
+				ElementType:         types.StringType,
+				MarkdownDescription: "Labels of the secret. Labels changed outside of Terraform show up as changes during plan. When not set, the labels of an existing secret are kept",
+				Optional:            true,
+			},
+			"secret_id": schema.StringAttribute{
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"payload_sha256": schema.StringAttribute{
+				MarkdownDescription: "Hex encoded SHA-256 digest of the payload",
+				Computed:            true,
+			},
+			"version": schema.StringAttribute{
+				MarkdownDescription: "Id of the latest secret version",
+				Computed:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+		},
+	}
+}
+
+func (s *SecretResource) ConfigValidators(ctx context.Context) []resource.ConfigValidator {
+	return []resource.ConfigValidator{
+		resourcevalidator.ExactlyOneOf(path.MatchRoot("payload"), path.MatchRoot("payload_base64"), path.MatchRoot("payload_file")),
+	}
+}
+
+func (s *SecretResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*ClearBladeGoogleProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *ClearBladeGoogleProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	s.client = providerData.client
+}
+
+func (s *SecretResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data SecretResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	labels, err := secretLabels(ctx, data.Labels)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid labels attribute", err.Error())
+		return
+	}
+	secretId := getSecretId(data.Namespace.ValueString(), data.Suffix.ValueString())
+	if err := createSecretWithLabels(ctx, s.client, data.ProjectId.ValueString(), secretId, labels); err != nil {
+		resp.Diagnostics.AddError("Failed to create secret", err.Error())
+		return
+	}
+	data.SecretId = types.StringValue(secretId)
+	if err := s.addVersion(ctx, &data); err != nil {
+		resp.Diagnostics.AddError("Failed to add secret version", err.Error())
+		return
+	}
+	tflog.Trace(ctx, "created and stored secret to GCP secrets")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (s *SecretResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data SecretResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	payload, err := getLatestSecretVersion(ctx, s.client, data.ProjectId.ValueString(), data.SecretId.ValueString())
+	if err != nil {
+		if isSecretNotFound(err) {
+			tflog.Warn(ctx, "Secret not found, removing from state")
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.AddError("Failed to get secret", err.Error())
+		return
+	}
+	version, err := getLatestSecretVersionId(ctx, s.client, data.ProjectId.ValueString(), data.SecretId.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to get secret version", err.Error())
+		return
+	}
+	// A version added outside of Terraform shows up as a payload change
+	data.PayloadSHA256 = types.StringValue(payloadSHA256(payload))
+	data.Version = types.StringValue(version)
+	// Labels changed outside of Terraform show up as a labels change, unless
+	// labels are not managed
+	if !data.Labels.IsNull() {
+		labels, err := getSecretLabels(ctx, s.client, data.ProjectId.ValueString(), data.SecretId.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddError("Failed to get secret labels", err.Error())
+			return
+		}
+		value, diags := types.MapValueFrom(ctx, types.StringType, labels)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		data.Labels = value
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (s *SecretResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data, state SecretResourceModel
+
+	// Read Terraform plan and prior state data into the models
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// ModifyPlan leaves the version unknown when the payload changed
+	if data.Version.IsUnknown() {
+		if err := s.addVersion(ctx, &data); err != nil {
+			resp.Diagnostics.AddError("Failed to add secret version", err.Error())
+			return
+		}
+	}
+	if !data.Labels.Equal(state.Labels) {
+		labels, err := secretLabels(ctx, data.Labels)
+		if err != nil {
+			resp.Diagnostics.AddError("Invalid labels attribute", err.Error())
+			return
+		}
+		if err := updateSecretLabels(ctx, s.client, data.ProjectId.ValueString(), data.SecretId.ValueString(), labels); err != nil {
+			resp.Diagnostics.AddError("Failed to update secret labels", err.Error())
+			return
+		}
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (s *SecretResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data SecretResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := deleteSecret(ctx, s.client, data.ProjectId.ValueString(), data.SecretId.ValueString()); err != nil {
+		resp.Diagnostics.AddError("Failed to delete secret", err.Error())
+		return
+	}
+}
+
+func (s *SecretResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to plan when the resource is destroyed.
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var plan SecretResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if plan.Payload.IsUnknown() || plan.PayloadBase64.IsUnknown() || plan.PayloadFile.IsUnknown() {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("version"), types.StringUnknown())...)
+		return
+	}
+
+	// The digest is planned so changes of payload_file contents are detected,
+	// and payload errors are reported before apply.
+	payload, err := plan.payload()
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid secret payload", err.Error())
+		return
+	}
+	if len(payload) > maxSecretPayloadSize {
+		resp.Diagnostics.AddError("Invalid secret payload",
+			fmt.Sprintf("payload is %d bytes, the limit is %d bytes", len(payload), maxSecretPayloadSize))
+		return
+	}
+	digest := payloadSHA256(payload)
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("payload_sha256"), types.StringValue(digest))...)
+
+	if req.State.Raw.IsNull() {
+		return
+	}
+	var state SecretResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if state.PayloadSHA256.ValueString() != digest {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("version"), types.StringUnknown())...)
+	}
+}
+
+// addVersion adds the payload as a new secret version.
+func (s *SecretResource) addVersion(ctx context.Context, data *SecretResourceModel) error {
+	payload, err := data.payload()
+	if err != nil {
+		return err
+	}
+	version, err := addSecretVersionWithId(ctx, s.client, data.ProjectId.ValueString(), data.SecretId.ValueString(), payload)
+	if err != nil {
+		return err
+	}
+	data.PayloadSHA256 = types.StringValue(payloadSHA256(payload))
+	data.Version = types.StringValue(version)
+	return nil
+}
+
+// payload returns the configured payload.
+func (d *SecretResourceModel) payload() ([]byte, error) {
+	switch {
+	case !d.PayloadBase64.IsNull():
+		payload, err := base64.StdEncoding.DecodeString(d.PayloadBase64.ValueString())
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode payload_base64: %w", err)
+		}
+		return payload, nil
+	case !d.PayloadFile.IsNull():
+		payload, err := os.ReadFile(d.PayloadFile.ValueString())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read payload_file: %w", err)
+		}
+		return payload, nil
+	}
+	return []byte(d.Payload.ValueString()), nil
+}
+
+func payloadSHA256(payload []byte) string {
+	digest := sha256.Sum256(payload)
+	return hex.EncodeToString(digest[:])
+}
@@ -0,0 +1,87 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/google/tink/go/keyset"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+	"github.com/google/tink/go/signature"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &SigningKeysetResource{}
+var _ resource.ResourceWithImportState = &SigningKeysetResource{}
+
+// signingKeyTemplates maps the supported algorithms to their key templates. Keys
+// use the RAW output prefix so signatures can be verified as plain ES256/EdDSA.
+var signingKeyTemplates = map[string]func() *tinkpb.KeyTemplate{
+	"ECDSA_P256": signature.ECDSAP256RawKeyTemplate,
+	"ED25519":    signature.ED25519KeyWithoutPrefixTemplate,
+}
+
+func NewSigningKeysetResource() resource.Resource {
+	return &SigningKeysetResource{keysetResource{
+		kind:      "signing keyset",
+		templates: signingKeyTemplates,
+		newModel:  func() keysetModel { return &SigningKeysetResourceModel{} },
+	}}
+}
+
+// SigningKeysetResource defines the resource implementation.
+type SigningKeysetResource struct {
+	keysetResource
+}
+
+// SigningKeysetResourceModel describes the resource data model.
+type SigningKeysetResourceModel struct {
+	KeysetResourceModel
+	PublicKeyset types.String `tfsdk:"public_keyset"`
+	JWKS         types.String `tfsdk:"jwks"`
+}
+
+func (s *SigningKeysetResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_signing_keyset"
+}
+
+func (s *SigningKeysetResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	attributes := keysetAttributes(schema.StringAttribute{
+		MarkdownDescription: "Signature algorithm, either `ECDSA_P256` or `ED25519`. Changing the algorithm rotates the keyset, " +
+			"a new key becomes primary and the existing keys stay in the keyset for verifying earlier signatures",
+		Required:   true,
+		Validators: []validator.String{stringvalidator.OneOf(keyTemplateNames(signingKeyTemplates)...)},
+	})
+	attributes["public_keyset"] = schema.StringAttribute{
+		MarkdownDescription: "Tink JSON public keyset for verifying signatures",
+		Computed:            true,
+	}
+	attributes["jwks"] = schema.StringAttribute{
+		MarkdownDescription: "JSON Web Key Set of the public keys",
+		Computed:            true,
+	}
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Tink signing keyset, for example for ClearBlade token signing. The private keyset is stored in GCP Secrets. " +
+			"Import with `<project_id>/<namespace>/<suffix>`",
+
+		Attributes: attributes,
+	}
+}
+
+func (d *SigningKeysetResourceModel) setKeyset(kh *keyset.Handle) error {
+	publicKeyset, err := publicKeysetJSON(kh)
+	if err != nil {
+		return err
+	}
+	jwks, err := jwksFromKeyset(kh)
+	if err != nil {
+		return err
+	}
+	d.PublicKeyset = types.StringValue(publicKeyset)
+	d.JWKS = types.StringValue(jwks)
+	return nil
+}
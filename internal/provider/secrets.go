@@ -2,9 +2,16 @@ package provider
 
 import (
 	"context"
+	"fmt"
+	"path"
+	"time"
 
 	secretmanager "cloud.google.com/go/secretmanager/apiv1"
 	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 func secretExists(ctx context.Context, client *secretmanager.Client, projectId, secretId string) bool {
@@ -16,15 +23,31 @@ func secretExists(ctx context.Context, client *secretmanager.Client, projectId,
 	return true
 }
 
+// isSecretNotFound reports whether err is the Secret Manager error for a
+// deleted secret or secret version.
+func isSecretNotFound(err error) bool {
+	return status.Code(err) == codes.NotFound
+}
+
 func createSecret(ctx context.Context, client *secretmanager.Client, projectId, secretId string) error {
+	return createSecretWithLabels(ctx, client, projectId, secretId, nil)
+}
+
+// createSecretWithLabels creates the secret with labels. The labels of an
+// existing secret are replaced, unless labels is nil.
+func createSecretWithLabels(ctx context.Context, client *secretmanager.Client, projectId, secretId string, labels map[string]string) error {
 	if secretExists(ctx, client, projectId, secretId) {
-		return nil
+		if labels == nil {
+			return nil
+		}
+		return updateSecretLabels(ctx, client, projectId, secretId, labels)
 	}
 	parent := "projects/" + projectId
 	createReq := &secretmanagerpb.CreateSecretRequest{
 		Parent:   parent,
 		SecretId: secretId,
 		Secret: &secretmanagerpb.Secret{
+			Labels: labels,
 			Replication: &secretmanagerpb.Replication{
 				Replication: &secretmanagerpb.Replication_Automatic_{
 					Automatic: &secretmanagerpb.Replication_Automatic{},
@@ -39,6 +62,12 @@ func createSecret(ctx context.Context, client *secretmanager.Client, projectId,
 }
 
 func addSecretVersion(ctx context.Context, client *secretmanager.Client, projectId, secretId string, data []byte) error {
+	_, err := addSecretVersionWithId(ctx, client, projectId, secretId, data)
+	return err
+}
+
+// addSecretVersionWithId adds a secret version and returns its Id, like "3".
+func addSecretVersionWithId(ctx context.Context, client *secretmanager.Client, projectId, secretId string, data []byte) (string, error) {
 	resource := getSecretResourceName(projectId, secretId)
 	addReq := &secretmanagerpb.AddSecretVersionRequest{
 		Parent: resource,
@@ -46,10 +75,20 @@ func addSecretVersion(ctx context.Context, client *secretmanager.Client, project
 			Data: data,
 		},
 	}
-	if _, err := client.AddSecretVersion(ctx, addReq); err != nil {
-		return err
+	version, err := client.AddSecretVersion(ctx, addReq)
+	if err != nil {
+		return "", err
 	}
-	return nil
+	return path.Base(version.Name), nil
+}
+
+// addSecretVersionIfChanged adds a secret version unless the latest one has the
+// same payload, so unchanged secrets do not restart the pods consuming them.
+func addSecretVersionIfChanged(ctx context.Context, client *secretmanager.Client, projectId, secretId string, payload []byte) error {
+	if latest, err := getLatestSecretVersion(ctx, client, projectId, secretId); err == nil && string(latest) == string(payload) {
+		return nil
+	}
+	return addSecretVersion(ctx, client, projectId, secretId, payload)
 }
 
 func getSecretResourceName(projectId, secretId string) string {
@@ -59,3 +98,76 @@ func getSecretResourceName(projectId, secretId string) string {
 func getSecretId(namespace, suffix string) string {
 	return namespace + suffix
 }
+
+func getLatestSecretVersion(ctx context.Context, client *secretmanager.Client, projectId, secretId string) ([]byte, error) {
+	resource := getSecretResourceName(projectId, secretId) + "/versions/latest"
+	rval, err := client.AccessSecretVersion(ctx, &secretmanagerpb.AccessSecretVersionRequest{Name: resource})
+	if err != nil {
+		return nil, err
+	}
+	return rval.Payload.Data, nil
+}
+
+func deleteSecret(ctx context.Context, client *secretmanager.Client, projectId, secretId string) error {
+	delReq := &secretmanagerpb.DeleteSecretRequest{
+		Name: getSecretResourceName(projectId, secretId),
+	}
+	return client.DeleteSecret(ctx, delReq)
+}
+
+// updateSecretLabels replaces the labels of a secret.
+func updateSecretLabels(ctx context.Context, client *secretmanager.Client, projectId, secretId string, labels map[string]string) error {
+	updateReq := &secretmanagerpb.UpdateSecretRequest{
+		Secret: &secretmanagerpb.Secret{
+			Name:   getSecretResourceName(projectId, secretId),
+			Labels: labels,
+		},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"labels"}},
+	}
+	_, err := client.UpdateSecret(ctx, updateReq)
+	return err
+}
+
+// getSecretLabels returns the labels of a secret.
+func getSecretLabels(ctx context.Context, client *secretmanager.Client, projectId, secretId string) (map[string]string, error) {
+	secret, err := client.GetSecret(ctx, &secretmanagerpb.GetSecretRequest{Name: getSecretResourceName(projectId, secretId)})
+	if err != nil {
+		return nil, err
+	}
+	return secret.Labels, nil
+}
+
+// secretLabels returns the configured labels of a secret. It returns nil when
+// labels are not configured, so the labels of an existing secret are kept.
+func secretLabels(ctx context.Context, labels types.Map) (map[string]string, error) {
+	if labels.IsNull() || labels.IsUnknown() {
+		return nil, nil
+	}
+	elements := map[string]string{}
+	if diags := labels.ElementsAs(ctx, &elements, false); diags.HasError() {
+		return nil, fmt.Errorf("failed to read labels")
+	}
+	return elements, nil
+}
+
+// getLatestSecretVersionCreateTime returns when the latest version of a secret
+// was added.
+func getLatestSecretVersionCreateTime(ctx context.Context, client *secretmanager.Client, projectId, secretId string) (time.Time, error) {
+	resource := getSecretResourceName(projectId, secretId) + "/versions/latest"
+	version, err := client.GetSecretVersion(ctx, &secretmanagerpb.GetSecretVersionRequest{Name: resource})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return version.CreateTime.AsTime(), nil
+}
+
+// getLatestSecretVersionId returns the Id of the latest version of a secret,
+// like "3".
+func getLatestSecretVersionId(ctx context.Context, client *secretmanager.Client, projectId, secretId string) (string, error) {
+	resource := getSecretResourceName(projectId, secretId) + "/versions/latest"
+	version, err := client.GetSecretVersion(ctx, &secretmanagerpb.GetSecretVersionRequest{Name: resource})
+	if err != nil {
+		return "", err
+	}
+	return path.Base(version.Name), nil
+}
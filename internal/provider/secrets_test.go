@@ -0,0 +1,52 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsSecretNotFound(t *testing.T) {
+	notFound := status.Error(codes.NotFound, "Secret [projects/p/secrets/s] not found or has no versions.")
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{notFound, true},
+		// readCASecret and the keyset reader wrap the Secret Manager error.
+		{fmt.Errorf("failed to get CA secret: %w", notFound), true},
+		{status.Error(codes.PermissionDenied, "denied"), false},
+		{errors.New("invalid keyset"), false},
+		{nil, false},
+	} {
+		if got := isSecretNotFound(tc.err); got != tc.want {
+			t.Errorf("isSecretNotFound(%v) = %t, want %t", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestSecretLabels(t *testing.T) {
+	ctx := context.Background()
+	for _, labels := range []types.Map{types.MapNull(types.StringType), types.MapUnknown(types.StringType)} {
+		got, err := secretLabels(ctx, labels)
+		if err != nil || got != nil {
+			t.Errorf("secretLabels(%v) = %v, %v, want nil", labels, got, err)
+		}
+	}
+
+	labels := types.MapValueMust(types.StringType, map[string]attr.Value{"team": types.StringValue("iot")})
+	got, err := secretLabels(ctx, labels)
+	if err != nil || len(got) != 1 || got["team"] != "iot" {
+		t.Errorf("secretLabels(%v) = %v, %v", labels, got, err)
+	}
+	got, err = secretLabels(ctx, types.MapValueMust(types.StringType, map[string]attr.Value{}))
+	if err != nil || got == nil || len(got) != 0 {
+		t.Errorf("secretLabels of an empty map = %#v, %v, want an empty map", got, err)
+	}
+}
@@ -0,0 +1,113 @@
+package provider
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// splitSecret splits secret into parts shares using Shamir's secret sharing over
+// GF(2^8). Any threshold of the shares reconstruct the secret with combineShares.
+// Each share is one byte longer than the secret, the trailing byte being the x
+// coordinate the share was evaluated at.
+func splitSecret(secret []byte, parts, threshold int) ([][]byte, error) {
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("cannot split an empty secret")
+	}
+	if threshold < 2 {
+		return nil, fmt.Errorf("threshold must be at least 2")
+	}
+	if parts < threshold {
+		return nil, fmt.Errorf("number of shares (%d) cannot be less than the threshold (%d)", parts, threshold)
+	}
+	if parts > 255 {
+		return nil, fmt.Errorf("number of shares cannot exceed 255")
+	}
+
+	shares := make([][]byte, parts)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+1)
+		shares[i][len(secret)] = byte(i + 1)
+	}
+	coefficients := make([]byte, threshold)
+	for idx, b := range secret {
+		if _, err := rand.Read(coefficients[1:]); err != nil {
+			return nil, err
+		}
+		coefficients[0] = b
+		for _, share := range shares {
+			x := share[len(secret)]
+			// Horner's method, starting from the highest degree coefficient.
+			y := coefficients[threshold-1]
+			for c := threshold - 2; c >= 0; c-- {
+				y = gfMul(y, x) ^ coefficients[c]
+			}
+			share[idx] = y
+		}
+	}
+	return shares, nil
+}
+
+// combineShares reconstructs a secret from shares produced by splitSecret. At
+// least threshold shares must be given, otherwise the result is garbage.
+func combineShares(shares [][]byte) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, fmt.Errorf("at least 2 shares are required")
+	}
+	length := len(shares[0])
+	if length < 2 {
+		return nil, fmt.Errorf("shares are too short")
+	}
+	xs := make([]byte, len(shares))
+	seen := map[byte]bool{}
+	for i, share := range shares {
+		if len(share) != length {
+			return nil, fmt.Errorf("all shares must be the same length")
+		}
+		x := share[length-1]
+		if x == 0 || seen[x] {
+			return nil, fmt.Errorf("share %d has an invalid or duplicate x coordinate", i)
+		}
+		seen[x] = true
+		xs[i] = x
+	}
+
+	secret := make([]byte, length-1)
+	for idx := range secret {
+		// Lagrange interpolation at x = 0. Subtraction is XOR in GF(2^8).
+		var result byte
+		for i, share := range shares {
+			basis := byte(1)
+			for j := range shares {
+				if i == j {
+					continue
+				}
+				basis = gfMul(basis, gfDiv(xs[j], xs[j]^xs[i]))
+			}
+			result ^= gfMul(share[idx], basis)
+		}
+		secret[idx] = result
+	}
+	return secret, nil
+}
+
+// gfMul multiplies two elements of GF(2^8) modulo the AES polynomial without
+// data dependent branches.
+func gfMul(a, b byte) byte {
+	var p byte
+	for i := 0; i < 8; i++ {
+		p ^= -(b & 1) & a
+		carry := -(a >> 7) & 0x1b
+		a = a<<1 ^ carry
+		b >>= 1
+	}
+	return p
+}
+
+// gfDiv divides a by b in GF(2^8), using b^254 as the inverse of b.
+func gfDiv(a, b byte) byte {
+	inv := b
+	for i := 0; i < 6; i++ {
+		inv = gfMul(gfMul(inv, inv), b)
+	}
+	return gfMul(a, gfMul(inv, inv))
+}
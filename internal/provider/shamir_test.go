@@ -0,0 +1,129 @@
+package provider
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGFMul(t *testing.T) {
+	// FIPS-197 section 4.2.
+	if got := gfMul(0x57, 0x83); got != 0xc1 {
+		t.Errorf("gfMul(0x57, 0x83) = %#x, want 0xc1", got)
+	}
+	if got := gfMul(0x57, 0x13); got != 0xfe {
+		t.Errorf("gfMul(0x57, 0x13) = %#x, want 0xfe", got)
+	}
+	for a := 0; a < 256; a++ {
+		if got := gfMul(byte(a), 0); got != 0 {
+			t.Fatalf("gfMul(%#x, 0) = %#x, want 0", a, got)
+		}
+		if got := gfMul(byte(a), 1); got != byte(a) {
+			t.Fatalf("gfMul(%#x, 1) = %#x, want %#x", a, got, a)
+		}
+	}
+}
+
+func TestGFDiv(t *testing.T) {
+	for a := 0; a < 256; a++ {
+		for b := 1; b < 256; b++ {
+			q := gfDiv(byte(a), byte(b))
+			if got := gfMul(q, byte(b)); got != byte(a) {
+				t.Fatalf("gfMul(gfDiv(%#x, %#x), %#x) = %#x, want %#x", a, b, b, got, a)
+			}
+		}
+	}
+}
+
+func TestSplitCombineEverySubset(t *testing.T) {
+	secret := []byte("ClearBlade master encryption key")
+	for _, tc := range []struct{ parts, threshold int }{
+		{2, 2},
+		{3, 2},
+		{5, 3},
+		{6, 6},
+	} {
+		shares, err := splitSecret(secret, tc.parts, tc.threshold)
+		if err != nil {
+			t.Fatalf("splitSecret(%d, %d): %v", tc.parts, tc.threshold, err)
+		}
+		if len(shares) != tc.parts {
+			t.Fatalf("splitSecret(%d, %d) returned %d shares", tc.parts, tc.threshold, len(shares))
+		}
+		for _, subset := range subsets(shares, tc.threshold) {
+			got, err := combineShares(subset)
+			if err != nil {
+				t.Fatalf("combineShares(%d of %d): %v", tc.threshold, tc.parts, err)
+			}
+			if !bytes.Equal(got, secret) {
+				t.Fatalf("combineShares(%d of %d) = %q, want %q", tc.threshold, tc.parts, got, secret)
+			}
+		}
+	}
+}
+
+func TestCombineBelowThreshold(t *testing.T) {
+	secret := []byte("ClearBlade master encryption key")
+	shares, err := splitSecret(secret, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, subset := range subsets(shares, 2) {
+		got, err := combineShares(subset)
+		if err != nil {
+			t.Fatalf("combineShares: %v", err)
+		}
+		if bytes.Equal(got, secret) {
+			t.Fatal("combineShares reconstructed the secret from fewer than threshold shares")
+		}
+	}
+}
+
+func TestCombineSharesRejectsInvalidX(t *testing.T) {
+	shares, err := splitSecret([]byte("secret"), 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	duplicate := [][]byte{shares[0], bytes.Clone(shares[0])}
+	if _, err := combineShares(duplicate); err == nil {
+		t.Error("combineShares accepted shares with duplicate x coordinates")
+	}
+
+	zero := bytes.Clone(shares[1])
+	zero[len(zero)-1] = 0
+	if _, err := combineShares([][]byte{shares[0], zero}); err == nil {
+		t.Error("combineShares accepted a share with a zero x coordinate")
+	}
+}
+
+func TestSplitSecretRejectsInvalidArguments(t *testing.T) {
+	for _, tc := range []struct {
+		name             string
+		secret           []byte
+		parts, threshold int
+	}{
+		{"empty secret", nil, 3, 2},
+		{"threshold below 2", []byte("secret"), 3, 1},
+		{"parts below threshold", []byte("secret"), 2, 3},
+		{"too many parts", []byte("secret"), 256, 2},
+	} {
+		if _, err := splitSecret(tc.secret, tc.parts, tc.threshold); err == nil {
+			t.Errorf("%s: splitSecret succeeded", tc.name)
+		}
+	}
+}
+
+// subsets returns every k-sized subset of shares.
+func subsets(shares [][]byte, k int) [][][]byte {
+	if k == 0 {
+		return [][][]byte{nil}
+	}
+	if len(shares) < k {
+		return nil
+	}
+	var result [][][]byte
+	for _, rest := range subsets(shares[1:], k-1) {
+		result = append(result, append([][]byte{shares[0]}, rest...))
+	}
+	return append(result, subsets(shares[1:], k)...)
+}